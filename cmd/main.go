@@ -1,8 +1,12 @@
 package main
 
 import (
+	"flag"
+	"fmt"
 	"log"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
@@ -10,7 +14,10 @@ import (
 	"github.com/joho/godotenv"
 	"github.com/user/roma/pkg/api"
 	"github.com/user/roma/pkg/db"
+	"github.com/user/roma/pkg/jobs"
 	"github.com/user/roma/pkg/middleware"
+	"github.com/user/roma/pkg/ratelimit"
+	"github.com/user/roma/pkg/storage"
 	"github.com/user/roma/pkg/utils"
 )
 
@@ -23,30 +30,93 @@ func main() {
 	// Инициализируем базу данных
 	db.InitDatabase()
 
-	// Создаем директорию для загрузки изображений, если ее нет
-	if err := os.MkdirAll(utils.ImageDir, 0755); err != nil {
-		log.Fatalf("Ошибка создания директории для загрузки изображений: %v", err)
+	// Назначаем администраторов из переменной окружения ADMIN_LOGINS (логины через запятую)
+	if admins := os.Getenv("ADMIN_LOGINS"); admins != "" {
+		if err := db.GrantAdminRoles(strings.Split(admins, ",")); err != nil {
+			log.Fatalf("Ошибка назначения администраторов: %v", err)
+		}
 	}
 
+	// Подключаем хранилище загруженных файлов (локальная директория или S3)
+	store, err := storage.FromEnv()
+	if err != nil {
+		log.Fatalf("Ошибка подключения хранилища файлов: %v", err)
+	}
+	utils.Store = store
+	utils.Refs = db.ImageRefs{}
+
+	// Команды обслуживания (например, gc-uploads) выполняются вместо запуска сервера
+	if len(os.Args) > 1 {
+		os.Exit(runCommand(os.Args[1], os.Args[2:]))
+	}
+
+	// Загружаем правила проверки содержимого и следим за изменениями файла правил
+	jobs.StartContentRulesReloader(jobs.ContentRulesFile(), jobs.ContentRulesReloadInterval)
+
+	// Запускаем фоновую публикацию запланированных карточек
+	jobs.StartCardPublisher(jobs.PublishInterval)
+
+	// Запускаем фоновое снятие истекших блокировок пользователей
+	jobs.StartSuspensionExpirer(jobs.SuspensionCheckInterval)
+
+	// Запускаем фоновую очистку корзины
+	jobs.StartTrashPurger(jobs.PurgeInterval, jobs.TrashRetention())
+
+	// Запускаем фоновое удаление загруженных файлов, на которые ничто не ссылается
+	jobs.StartUploadCollector(jobs.UploadGCInterval, jobs.UploadGCGrace(), jobs.UploadGCDryRun())
+
+	// Запускаем фоновое удаление истекших возобновляемых загрузок
+	jobs.StartUploadExpirer(jobs.ResumableUploadCheckInterval)
+
+	// Запускаем фоновую запись просмотров карточек
+	jobs.StartViewRecorder(jobs.ViewFlushInterval, jobs.ViewDedupWindow())
+
+	// Задаем параметры обработки загружаемых изображений
+	utils.LoadImageSizes()
+	utils.LoadTransformSizes()
+	utils.LoadTransformURL()
+	utils.LoadMetadataOptions()
+	utils.LoadDecodeLimits()
+	utils.LoadUploadExpiration()
+	utils.LoadPartialUploadDir()
+
 	// Создаем экземпляр Fiber
+	// Тела запросов читаются потоком, чтобы части возобновляемых загрузок записывались
+	// по мере получения; размер остальных запросов ограничивает middleware.LimitBody
+	const bodyLimit = 10 * 1024 * 1024 // 10MB
 	app := fiber.New(fiber.Config{
-		BodyLimit: 10 * 1024 * 1024, // 10MB
+		BodyLimit:                    bodyLimit,
+		StreamRequestBody:            true,
+		DisablePreParseMultipartForm: true,
 	})
 
 	// Добавляем middleware
 	app.Use(logger.New())
-	app.Use(cors.New())
+	app.Use(middleware.LimitBody(bodyLimit))
+	app.Use(cors.New(cors.Config{
+		// Заголовки протокола tus должны быть доступны клиентам в браузере
+		ExposeHeaders: "Location, Tus-Resumable, Tus-Version, Tus-Extension, Tus-Max-Size, Upload-Offset, Upload-Length, Upload-Expires, Upload-Metadata",
+	}))
+
+	// Загруженные файлы из хранилища; клиентам с поддержкой WebP отдается копия в WebP, если она есть
+	app.Get("/uploads/:filename", api.ServeUpload)
+
+	// Ограничиваем частоту запросов к API: чтение, изменения и загрузки файлов учитываются
+	// отдельно, регистрация и вход дополнительно ограничены строже
+	rateLimits := middleware.LoadRateLimitPolicies()
+	rateLimitStore := ratelimit.NewMemoryStore()
+	authRateLimit := middleware.RateLimit(rateLimitStore, rateLimits.Auth)
 
-	// Статические файлы для загрузок
-	app.Static("/uploads", "./uploads")
+	// Преобразованные по запросу изображения (по подписанным ссылкам)
+	app.Get("/img/:filename", middleware.RateLimit(rateLimitStore, rateLimits.Reads), api.TransformImage)
 
 	// Определяем маршруты API
-	apiRouter := app.Group("/api")
+	apiRouter := app.Group("/api", middleware.RateLimitRequests(rateLimitStore, rateLimits))
 
 	// Маршруты аутентификации (публичные)
 	auth := apiRouter.Group("/auth")
-	auth.Post("/register", api.Register)
-	auth.Post("/login", api.Login)
+	auth.Post("/register", authRateLimit, api.Register)
+	auth.Post("/login", authRateLimit, api.Login)
 	auth.Get("/me", middleware.Auth(), api.Me)
 
 	// Маршруты профиля (требуют аутентификации)
@@ -54,17 +124,79 @@ func main() {
 	profile.Put("/", api.UpdateProfile)
 	profile.Post("/image", api.UploadProfileImage)
 	profile.Post("/banner", api.UploadProfileBanner)
+	profile.Get("/stats", api.GetProfileStats)
+
+	// Маршруты пользователей
+	users := apiRouter.Group("/users")
+	users.Post("/:userId/follow", middleware.Auth(), api.FollowUser)               // Подписка на пользователя (требует аутентификации)
+	users.Delete("/:userId/follow", middleware.Auth(), api.UnfollowUser)           // Отмена подписки (требует аутентификации)
+	users.Get("/:userId/mentions", middleware.OptionalAuth(), api.GetUserMentions) // Карточки с упоминанием пользователя (публичный)
 
 	// Маршруты карточек
 	cards := apiRouter.Group("/cards")
-	cards.Get("/", api.GetCards)                                     // Получение всех карточек (публичный)
-	cards.Get("/:cardId", api.GetCard)                               // Получение карточки по ID (публичный)
-	cards.Get("/user/:userId", api.GetUserCards)                     // Получение карточек пользователя (публичный)
-	cards.Post("/", middleware.Auth(), api.CreateCard)               // Создание карточки (требует аутентификации)
-	cards.Put("/:cardId", middleware.Auth(), api.UpdateCard)         // Обновление карточки (требует аутентификации)
-	cards.Delete("/:cardId", middleware.Auth(), api.DeleteCard)      // Удаление карточки (требует аутентификации)
-	cards.Post("/:cardId/like", middleware.Auth(), api.LikeCard)     // Лайк карточки (требует аутентификации)
-	cards.Delete("/:cardId/like", middleware.Auth(), api.UnlikeCard) // Удаление лайка (требует аутентификации)
+	cards.Get("/", middleware.OptionalAuth(), api.GetCards)                 // Получение всех карточек (публичный)
+	cards.Get("/trash", middleware.Auth(), api.GetTrashCards)               // Корзина текущего пользователя (требует аутентификации)
+	cards.Get("/:cardId", middleware.OptionalAuth(), api.GetCard)           // Получение карточки по ID (публичный)
+	cards.Get("/user/:userId", middleware.OptionalAuth(), api.GetUserCards) // Получение карточек пользователя (публичный)
+	cards.Post("/", middleware.Auth(), api.CreateCard)                      // Создание карточки (требует аутентификации)
+	cards.Put("/:cardId", middleware.Auth(), api.UpdateCard)                // Обновление карточки (требует аутентификации)
+	cards.Delete("/:cardId", middleware.Auth(), api.DeleteCard)             // Удаление карточки (требует аутентификации)
+	cards.Post("/:cardId/like", middleware.Auth(), api.LikeCard)            // Лайк карточки (требует аутентификации)
+	cards.Delete("/:cardId/like", middleware.Auth(), api.UnlikeCard)        // Удаление лайка (требует аутентификации)
+	cards.Post("/:cardId/publish", middleware.Auth(), api.PublishCard)      // Публикация карточки (требует аутентификации)
+	cards.Post("/:cardId/unpublish", middleware.Auth(), api.UnpublishCard)  // Снятие с публикации (требует аутентификации)
+	cards.Post("/:cardId/restore", middleware.Auth(), api.RestoreCard)      // Восстановление из корзины (требует аутентификации)
+	cards.Post("/preview", middleware.Auth(), api.PreviewCard)              // Предпросмотр Markdown (требует аутентификации)
+
+	// Галерея изображений карточки (требует аутентификации)
+	cards.Put("/:cardId/images/order", middleware.Auth(), api.ReorderCardImages)     // Изменение порядка изображений (требует аутентификации)
+	cards.Delete("/:cardId/images/:imageId", middleware.Auth(), api.RemoveCardImage) // Удаление изображения (требует аутентификации)
+
+	// История версий карточек (требует аутентификации)
+	cards.Get("/:cardId/revisions", middleware.Auth(), api.GetCardRevisions)                  // История версий (требует аутентификации)
+	cards.Get("/:cardId/revisions/diff", middleware.Auth(), api.GetCardRevisionDiff)          // Разница между версиями (требует аутентификации)
+	cards.Post("/:cardId/revisions/:rev/restore", middleware.Auth(), api.RestoreCardRevision) // Восстановление версии (требует аутентификации)
+
+	// Репосты карточек (требуют аутентификации)
+	cards.Post("/:cardId/repost", middleware.Auth(), api.RepostCard)     // Репост карточки (требует аутентификации)
+	cards.Delete("/:cardId/repost", middleware.Auth(), api.UnrepostCard) // Отмена репоста (требует аутентификации)
+
+	// Закрепленные карточки профиля (требуют аутентификации)
+	cards.Put("/pins/order", middleware.Auth(), api.ReorderPinnedCards) // Изменение порядка закрепленных карточек (требует аутентификации)
+	cards.Post("/:cardId/pin", middleware.Auth(), api.PinCard)          // Закрепление карточки (требует аутентификации)
+	cards.Delete("/:cardId/pin", middleware.Auth(), api.UnpinCard)      // Открепление карточки (требует аутентификации)
+
+	// Статистика карточки для автора (требует аутентификации)
+	cards.Get("/:cardId/stats", middleware.Auth(), api.GetCardStats) // Просмотры и лайки по дням (требует аутентификации)
+
+	// Жалобы на карточки (требуют аутентификации)
+	cards.Post("/:cardId/report", middleware.Auth(), api.ReportCard) // Жалоба на карточку (требует аутентификации)
+
+	// Возобновляемые загрузки изображений по протоколу tus; ID завершенной загрузки указывается
+	// в полях *_upload_id при создании карточки или смене изображения профиля. Части хранятся
+	// в utils.PartialUploadDir: при нескольких серверах она общая, либо нужна sticky-маршрутизация
+	uploads := apiRouter.Group("/uploads")
+	uploads.Options("/", api.UploadOptions)                           // Параметры протокола tus (публичный)
+	uploads.Post("/", middleware.Auth(), api.CreateUpload)            // Создание загрузки (требует аутентификации)
+	uploads.Head("/:uploadId", middleware.Auth(), api.HeadUpload)     // Количество полученных байт (требует аутентификации)
+	uploads.Get("/:uploadId", middleware.Auth(), api.GetUpload)       // Состояние загрузки в JSON (требует аутентификации)
+	uploads.Patch("/:uploadId", middleware.Auth(), api.PatchUpload)   // Отправка части файла (требует аутентификации)
+	uploads.Delete("/:uploadId", middleware.Auth(), api.DeleteUpload) // Удаление загрузки (требует аутентификации)
+
+	// Подписанные ссылки на преобразованные изображения (требуют аутентификации)
+	apiRouter.Get("/images/:filename/url", middleware.Auth(), api.GetImageTransformURL) // Ссылка на изображение нужного размера (требует аутентификации)
+
+	// Маршруты модерации (требуют прав администратора)
+	moderation := apiRouter.Group("/moderation", middleware.Auth(), middleware.Admin())
+	moderation.Get("/reports", api.GetReports)
+	moderation.Get("/reports/:reportId", api.GetReport)
+	moderation.Post("/reports/:reportId/claim", api.ClaimReport)
+	moderation.Post("/reports/:reportId/resolve", api.ResolveReport)
+	moderation.Get("/log", api.GetModerationLog)
+	moderation.Post("/users/:userId/suspend", api.SuspendUser)
+	moderation.Delete("/users/:userId/suspend", api.UnsuspendUser)
+	moderation.Post("/users/:userId/shadowban", api.ShadowBanUser)
+	moderation.Delete("/users/:userId/shadowban", api.UnshadowBanUser)
 
 	// Получаем порт из переменных окружения или используем порт по умолчанию
 	port := os.Getenv("PORT")
@@ -76,3 +208,44 @@ func main() {
 	log.Printf("Сервер запущен на порту %s", port)
 	log.Fatal(app.Listen(":" + port))
 }
+
+// runCommand выполняет команду обслуживания и возвращает код завершения процесса
+func runCommand(name string, args []string) int {
+	switch name {
+	case "gc-uploads":
+		return gcUploads(args)
+	default:
+		fmt.Fprintf(os.Stderr, "Неизвестная команда %q, доступные команды: gc-uploads\n", name)
+		return 2
+	}
+}
+
+// gcUploads находит и удаляет загруженные файлы, на которые ничто не ссылается:
+//
+//	roma gc-uploads [-dry-run] [-grace 24h]
+func gcUploads(args []string) int {
+	flags := flag.NewFlagSet("gc-uploads", flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "только перечислить неиспользуемые файлы, не удаляя их")
+	grace := flags.Duration("grace", jobs.UploadGCGrace(), "не удалять файлы моложе этого срока")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	report, err := jobs.CollectOrphanUploads(*grace, *dryRun)
+	for _, file := range report.Orphans {
+		fmt.Printf("%s\t%d\t%s\n", file.Name, file.Size, file.ModTime.Format(time.RFC3339))
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Ошибка: %v\n", err)
+		return 1
+	}
+
+	if *dryRun {
+		fmt.Printf("Неиспользуемых файлов: %d (%d байт), моложе %s: %d. Файлы не удалялись.\n",
+			len(report.Orphans), report.Bytes, *grace, report.Kept)
+	} else {
+		fmt.Printf("Удалено неиспользуемых файлов: %d (%d байт), оставлено до истечения %s: %d\n",
+			report.Removed, report.Bytes, *grace, report.Kept)
+	}
+	return 0
+}
@@ -3,6 +3,7 @@ package api
 import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/user/roma/pkg/db"
+	"github.com/user/roma/pkg/middleware"
 	"github.com/user/roma/pkg/models"
 	"github.com/user/roma/pkg/utils"
 )
@@ -72,6 +73,11 @@ func Login(c *fiber.Ctx) error {
 		})
 	}
 
+	// Заблокированным пользователям токен не выдается
+	if user.IsSuspended() {
+		return c.Status(fiber.StatusForbidden).JSON(middleware.SuspendedResponse(user))
+	}
+
 	// Генерируем JWT токен
 	token, err := utils.GenerateJWT(user)
 	if err != nil {
@@ -1,14 +1,14 @@
 package api
 
 import (
+	"errors"
 	"fmt"
 	"log"
-	"net/http"
-	"os"
-	"path/filepath"
 	"strconv"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/user/roma/pkg/contentcheck"
 	"github.com/user/roma/pkg/db"
 	"github.com/user/roma/pkg/models"
 	"github.com/user/roma/pkg/utils"
@@ -36,87 +36,47 @@ func CreateCard(c *fiber.Ctx) error {
 		})
 	}
 
-	// Создаем карточку без изображения
-	var imagePath string
-	var err error
-
-	// Получаем файл из запроса (если есть)
-	file, err := c.FormFile("image")
-	if err == nil && file != nil { // Если файл предоставлен
-		// Проверяем размер файла
-		if file.Size > utils.MaxImageSize {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": "размер изображения превышает максимально допустимый",
-			})
-		}
-
-		// Создаем директорию для временных файлов, если она не существует
-		if err := os.MkdirAll("./temp", 0755); err != nil {
-			log.Printf("Ошибка при создании директории для временных файлов: %v", err)
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error": "ошибка при обработке файла",
-			})
-		}
-
-		// Создаем уникальное имя для временного файла
-		tempPath := fmt.Sprintf("./temp/card_%s%s",
-			utils.GenerateRandomString(8), filepath.Ext(file.Filename))
-
-		// Сохраняем загруженный файл во временную директорию
-		if err := c.SaveFile(file, tempPath); err != nil {
-			log.Printf("Ошибка при сохранении файла: %v", err)
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error": "ошибка при сохранении файла",
-			})
-		}
-		defer os.Remove(tempPath) // Удаляем временный файл после завершения
-
-		// Читаем файл и сохраняем его с правильным именем в директории uploads
-		fileData, err := os.ReadFile(tempPath)
-		if err != nil {
-			log.Printf("Ошибка при чтении временного файла: %v", err)
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error": "ошибка при обработке файла",
-			})
-		}
-
-		// Определяем тип контента
-		contentType := http.DetectContentType(fileData)
-		var ext string
-		switch contentType {
-		case "image/jpeg":
-			ext = ".jpg"
-		case "image/png":
-			ext = ".png"
-		case "image/gif":
-			ext = ".gif"
-		default:
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": fmt.Sprintf("неподдерживаемый формат изображения: %s", contentType),
-			})
-		}
+	// Определяем статус публикации (по умолчанию карточка публикуется сразу)
+	status, publishAt, err := parseCardStatus(c.FormValue("status"), c.FormValue("publish_at"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
 
-		// Генерируем имя файла и сохраняем его
-		filename := fmt.Sprintf("card_%s%s", utils.GenerateRandomString(8), ext)
-		savePath := filepath.Join(utils.ImageDir, filename)
+	// Определяем видимость карточки (по умолчанию карточка видна всем)
+	visibility, err := parseCardVisibility(c.FormValue("visibility", models.CardVisibilityPublic))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
 
-		// Создаем директорию для загрузок, если она не существует
-		if err := os.MkdirAll(utils.ImageDir, 0755); err != nil {
-			log.Printf("Ошибка при создании директории для загрузок: %v", err)
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error": "ошибка при сохранении файла",
+	// Карточка-цитата может ссылаться только на видимую пользователю карточку
+	quoteCardID := c.FormValue("quote_card_id")
+	if quoteCardID != "" {
+		quoted, err := db.GetCardByID(quoteCardID)
+		if err != nil || !canViewCard(quoted, user.ID) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "цитируемая карточка не найдена",
 			})
 		}
+	}
 
-		// Копируем файл
-		if err := os.WriteFile(savePath, fileData, 0644); err != nil {
-			log.Printf("Ошибка при сохранении файла: %v", err)
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error": "ошибка при сохранении файла",
-			})
-		}
+	// Проверяем текст правилами содержимого
+	check := contentcheck.Check(contentcheck.Input{
+		Kind:   contentcheck.KindCard,
+		UserID: user.ID,
+		Fields: append([]string{title, description, text}, cardImageTexts(c)...),
+	})
+	if check.Action == contentcheck.ActionReject {
+		return contentRejected(c, check)
+	}
 
-		imagePath = filename
+	// Сохраняем загруженные изображения (если есть)
+	uploads, err := saveCardImageUploads(c)
+	if err != nil {
+		return errorResponse(c, err)
 	}
 
 	// Создаем карточку в базе данных
@@ -124,40 +84,33 @@ func CreateCard(c *fiber.Ctx) error {
 		Title:       title,
 		Description: description,
 		Text:        text,
-		Image:       imagePath,
+		Images:      uploads.All(),
+		Visibility:  visibility,
+		Status:      status,
+		PublishAt:   publishAt,
+		QuoteCardID: quoteCardID,
 	}
 
 	card, err := db.CreateCard(cardCreate, user.ID, user.Login)
 	if err != nil {
-		// В случае ошибки удаляем загруженное изображение
-		if imagePath != "" {
-			if err := utils.RemoveImage(imagePath); err != nil {
-				log.Printf("Ошибка при удалении изображения: %v", err)
-			}
-		}
+		// В случае ошибки удаляем загруженные изображения
+		uploads.Remove()
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": fmt.Sprintf("ошибка создания карточки: %v", err),
 		})
 	}
+	uploads.Commit(nil)
 
-	// Базовый URL для изображений
-	baseURL := BaseImagesURL
+	// Карточка с подозрительным текстом скрывается до проверки модератором
+	if check.Action == contentcheck.ActionHold {
+		holdCardIfNeeded(card.ID, check)
+		if held, err := db.GetCardByID(card.ID); err == nil {
+			card = held
+		}
+	}
 
 	// Формируем ответ
-	cardResponse := models.CardResponse{
-		ID:          card.ID,
-		UserID:      card.UserID,
-		UserName:    card.UserName,
-		Image:       getImageURL(baseURL, card.Image),
-		Title:       card.Title,
-		Description: card.Description,
-		Text:        card.Text,
-		Likes:       card.Likes,
-		IsLiked:     false,
-		CreatedAt:   card.CreatedAt,
-	}
-
-	return c.Status(fiber.StatusCreated).JSON(cardResponse)
+	return c.Status(fiber.StatusCreated).JSON(toCardResponse(card, false, user.ID))
 }
 
 // GetCards получает все карточки с пагинацией
@@ -246,35 +199,24 @@ func GetCard(c *fiber.Ctx) error {
 		})
 	}
 
-	// Проверяем, лайкнул ли текущий пользователь карточку
-	isLiked := false
+	// Получаем текущего пользователя, если авторизован
+	var currentUserID string
 	if user, ok := c.Locals("user").(*models.User); ok {
-		for _, likedBy := range card.LikedBy {
-			if likedBy == user.ID {
-				isLiked = true
-				break
-			}
-		}
+		currentUserID = user.ID
+	}
+
+	// Проверяем, что карточка видна текущему пользователю
+	if !canViewCard(card, currentUserID) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "карточка не найдена",
+		})
 	}
 
-	// Базовый URL для изображений
-	baseURL := BaseImagesURL
+	// Учитываем просмотр (запись в статистику выполняется в фоне)
+	trackCardView(c, card, currentUserID)
 
 	// Формируем ответ
-	cardResponse := models.CardResponse{
-		ID:          card.ID,
-		UserID:      card.UserID,
-		UserName:    card.UserName,
-		Image:       getImageURL(baseURL, card.Image),
-		Title:       card.Title,
-		Description: card.Description,
-		Text:        card.Text,
-		Likes:       card.Likes,
-		IsLiked:     isLiked,
-		CreatedAt:   card.CreatedAt,
-	}
-
-	return c.Status(fiber.StatusOK).JSON(cardResponse)
+	return c.Status(fiber.StatusOK).JSON(toCardResponse(card, isLikedBy(card, currentUserID), currentUserID))
 }
 
 // UpdateCard обновляет карточку
@@ -322,131 +264,96 @@ func UpdateCard(c *fiber.Ctx) error {
 		})
 	}
 
+	// Проверяем итоговый текст правилами содержимого
+	check := contentcheck.Check(contentcheck.Input{
+		Kind:   contentcheck.KindCard,
+		UserID: user.ID,
+		CardID: cardID,
+		Fields: append([]string{title, description, text}, cardImageTexts(c)...),
+	})
+	if check.Action == contentcheck.ActionReject {
+		return contentRejected(c, check)
+	}
+
 	// Создаем объект с данными для обновления
 	cardUpdate := models.CardUpdate{
 		Title:       title,
 		Description: description,
 		Text:        text,
-		Image:       card.Image, // По умолчанию оставляем текущее изображение
+		Visibility:  card.Visibility,
+		Status:      card.Status,
+		PublishAt:   card.PublishAt,
 	}
 
-	// Если есть новое изображение, сохраняем его и удаляем старое
-	var newImagePath string
-
-	// Получаем файл из запроса (если есть)
-	file, err := c.FormFile("image")
-	if err == nil && file != nil { // Если файл предоставлен
-		// Проверяем размер файла
-		if file.Size > utils.MaxImageSize {
+	// Меняем видимость, только если она передана в запросе
+	if visibilityValue := c.FormValue("visibility"); visibilityValue != "" {
+		cardUpdate.Visibility, err = parseCardVisibility(visibilityValue)
+		if err != nil {
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": "размер изображения превышает максимально допустимый",
-			})
-		}
-
-		// Создаем директорию для временных файлов, если она не существует
-		if err := os.MkdirAll("./temp", 0755); err != nil {
-			log.Printf("Ошибка при создании директории для временных файлов: %v", err)
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error": "ошибка при обработке файла",
-			})
-		}
-
-		// Создаем уникальное имя для временного файла
-		tempPath := fmt.Sprintf("./temp/card_%s%s",
-			utils.GenerateRandomString(8), filepath.Ext(file.Filename))
-
-		// Сохраняем загруженный файл во временную директорию
-		if err := c.SaveFile(file, tempPath); err != nil {
-			log.Printf("Ошибка при сохранении файла: %v", err)
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error": "ошибка при сохранении файла",
+				"error": err.Error(),
 			})
 		}
-		defer os.Remove(tempPath) // Удаляем временный файл после завершения
+	}
 
-		// Читаем файл и сохраняем его с правильным именем в директории uploads
-		fileData, err := os.ReadFile(tempPath)
+	// Меняем статус публикации, только если он передан в запросе
+	statusValue, publishAtValue := c.FormValue("status"), c.FormValue("publish_at")
+	if statusValue != "" || publishAtValue != "" {
+		cardUpdate.Status, cardUpdate.PublishAt, err = parseCardStatus(statusValue, publishAtValue)
 		if err != nil {
-			log.Printf("Ошибка при чтении временного файла: %v", err)
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error": "ошибка при обработке файла",
-			})
-		}
-
-		// Определяем тип контента
-		contentType := http.DetectContentType(fileData)
-		var ext string
-		switch contentType {
-		case "image/jpeg":
-			ext = ".jpg"
-		case "image/png":
-			ext = ".png"
-		case "image/gif":
-			ext = ".gif"
-		default:
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": fmt.Sprintf("неподдерживаемый формат изображения: %s", contentType),
+				"error": err.Error(),
 			})
 		}
+	}
 
-		// Генерируем имя файла и сохраняем его
-		newImagePath = fmt.Sprintf("card_%s%s", utils.GenerateRandomString(8), ext)
-		savePath := filepath.Join(utils.ImageDir, newImagePath)
-
-		// Создаем директорию для загрузок, если она не существует
-		if err := os.MkdirAll(utils.ImageDir, 0755); err != nil {
-			log.Printf("Ошибка при создании директории для загрузок: %v", err)
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error": "ошибка при сохранении файла",
-			})
-		}
+	// Сохраняем загруженные изображения (если есть)
+	uploads, err := saveCardImageUploads(c)
+	if err != nil {
+		return errorResponse(c, err)
+	}
+	cardUpdate.CoverImage = uploads.Cover
+	cardUpdate.NewImages = uploads.Images
 
-		// Копируем файл
-		if err := os.WriteFile(savePath, fileData, 0644); err != nil {
-			log.Printf("Ошибка при сохранении файла: %v", err)
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error": "ошибка при сохранении файла",
-			})
-		}
+	// Если запрос содержит явное указание удалить изображения, очищаем галерею
+	// (файлы остаются для истории версий)
+	cardUpdate.RemoveImages = c.FormValue("remove_image", "false") == "true"
 
-		// Если было старое изображение, удаляем его
-		if card.Image != "" && card.Image != newImagePath {
-			if err := utils.RemoveImage(card.Image); err != nil {
-				log.Printf("Ошибка при удалении старого изображения: %v", err)
-				// Продолжаем работу, не критическая ошибка
-			}
-		}
+	// Проверяем итоговое количество изображений в галерее
+	total := len(card.Images) + len(uploads.Images)
+	if cardUpdate.RemoveImages {
+		total = uploads.Count()
+	} else if uploads.Cover != nil && len(card.Images) == 0 {
+		total++
+	}
+	if total > utils.MaxCardImages {
+		uploads.Remove()
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("карточка может содержать не более %d изображений", utils.MaxCardImages),
+		})
+	}
 
-		// Обновляем путь к изображению
-		cardUpdate.Image = newImagePath
-	} else {
-		// Если запрос содержит явное указание удалить изображение
-		removeImage := c.FormValue("remove_image", "false")
-		if removeImage == "true" {
-			// Удаляем старое изображение, если оно было
-			if card.Image != "" {
-				if err := utils.RemoveImage(card.Image); err != nil {
-					log.Printf("Ошибка при удалении старого изображения: %v", err)
-					// Продолжаем работу, не критическая ошибка
-				}
-			}
-			cardUpdate.Image = "" // Очищаем путь к изображению
-		}
+	// Запоминаем файлы карточки, чтобы не учитывать повторно загруженные изображения дважды
+	existingImages, err := db.GetCardImageFiles(cardID)
+	if err != nil {
+		uploads.Remove()
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": fmt.Sprintf("ошибка получения изображений карточки: %v", err),
+		})
 	}
 
 	// Обновляем карточку в базе данных
 	err = db.UpdateCard(cardID, cardUpdate)
 	if err != nil {
-		// В случае ошибки удаляем новое изображение, если оно было загружено
-		if newImagePath != "" {
-			if err := utils.RemoveImage(newImagePath); err != nil {
-				log.Printf("Ошибка при удалении изображения: %v", err)
-			}
-		}
+		// В случае ошибки удаляем новые изображения, если они были загружены
+		uploads.Remove()
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": fmt.Sprintf("ошибка обновления карточки: %v", err),
 		})
 	}
+	uploads.Commit(existingImages)
+
+	// Карточка с подозрительным текстом скрывается до проверки модератором
+	holdCardIfNeeded(cardID, check)
 
 	// Получаем обновленную карточку
 	updatedCard, err := db.GetCardByID(cardID)
@@ -456,32 +363,8 @@ func UpdateCard(c *fiber.Ctx) error {
 		})
 	}
 
-	// Базовый URL для изображений
-	baseURL := BaseImagesURL
-
 	// Формируем ответ
-	cardResponse := models.CardResponse{
-		ID:          updatedCard.ID,
-		UserID:      updatedCard.UserID,
-		UserName:    updatedCard.UserName,
-		Image:       getImageURL(baseURL, updatedCard.Image),
-		Title:       updatedCard.Title,
-		Description: updatedCard.Description,
-		Text:        updatedCard.Text,
-		Likes:       updatedCard.Likes,
-		IsLiked:     false, // По умолчанию false, обновляется ниже
-		CreatedAt:   updatedCard.CreatedAt,
-	}
-
-	// Проверяем, лайкнул ли текущий пользователь карточку
-	for _, likedBy := range updatedCard.LikedBy {
-		if likedBy == user.ID {
-			cardResponse.IsLiked = true
-			break
-		}
-	}
-
-	return c.Status(fiber.StatusOK).JSON(cardResponse)
+	return c.Status(fiber.StatusOK).JSON(toCardResponse(updatedCard, isLikedBy(updatedCard, user.ID), user.ID))
 }
 
 // DeleteCard удаляет карточку
@@ -517,13 +400,8 @@ func DeleteCard(c *fiber.Ctx) error {
 		})
 	}
 
-	// Удаляем изображение карточки, если оно есть
-	if card.Image != "" {
-		utils.RemoveImage(card.Image)
-	}
-
-	// Удаляем карточку из базы данных
-	err = db.DeleteCard(cardID)
+	// Перемещаем карточку в корзину; окончательно она удаляется фоновой задачей
+	err = db.SoftDeleteCard(cardID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "ошибка удаления карточки",
@@ -531,10 +409,84 @@ func DeleteCard(c *fiber.Ctx) error {
 	}
 
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
-		"message": "карточка успешно удалена",
+		"message": "карточка перемещена в корзину",
 	})
 }
 
+// GetTrashCards получает карточки текущего пользователя из корзины с пагинацией
+func GetTrashCards(c *fiber.Ctx) error {
+	// Получаем пользователя из локального хранилища (установленного middleware)
+	user, ok := c.Locals("user").(*models.User)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "пользователь не найден",
+		})
+	}
+
+	// Получаем параметры пагинации из запроса
+	page, err := strconv.Atoi(c.Query("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	limit, err := strconv.Atoi(c.Query("limit", "4"))
+	if err != nil || limit < 1 {
+		limit = 4
+	}
+
+	// Получаем карточки из корзины
+	response, err := db.GetTrashCards(user.ID, page, limit)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "ошибка получения корзины",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(response)
+}
+
+// RestoreCard возвращает карточку из корзины
+func RestoreCard(c *fiber.Ctx) error {
+	// Получаем пользователя из локального хранилища (установленного middleware)
+	user, ok := c.Locals("user").(*models.User)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "пользователь не найден",
+		})
+	}
+
+	// Получаем ID карточки из URL
+	cardID := c.Params("cardId")
+	if cardID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "ID карточки не указан",
+		})
+	}
+
+	// Получаем карточку из корзины
+	card, err := db.GetDeletedCardByID(cardID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "карточка не найдена в корзине",
+		})
+	}
+
+	// Проверяем, что карточка принадлежит текущему пользователю
+	if card.UserID != user.ID {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "нет прав на восстановление этой карточки",
+		})
+	}
+
+	if err := db.RestoreCard(cardID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "ошибка восстановления карточки",
+		})
+	}
+
+	return respondWithCard(c, cardID, user.ID)
+}
+
 // LikeCard добавляет лайк карточке
 func LikeCard(c *fiber.Ctx) error {
 	// Получаем пользователя из локального хранилища (установленного middleware)
@@ -554,8 +506,8 @@ func LikeCard(c *fiber.Ctx) error {
 	}
 
 	// Проверяем существование карточки
-	_, err := db.GetCardByID(cardID)
-	if err != nil {
+	card, err := db.GetCardByID(cardID)
+	if err != nil || !canViewCard(card, user.ID) {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "карточка не найдена",
 		})
@@ -577,21 +529,8 @@ func LikeCard(c *fiber.Ctx) error {
 		})
 	}
 
-	// Формируем ответ
-	cardResponse := models.CardResponse{
-		ID:          updatedCard.ID,
-		UserID:      updatedCard.UserID,
-		UserName:    updatedCard.UserName,
-		Image:       updatedCard.Image,
-		Title:       updatedCard.Title,
-		Description: updatedCard.Description,
-		Text:        updatedCard.Text,
-		Likes:       updatedCard.Likes,
-		IsLiked:     true, // Мы только что лайкнули
-		CreatedAt:   updatedCard.CreatedAt,
-	}
-
-	return c.Status(fiber.StatusOK).JSON(cardResponse)
+	// Формируем ответ (мы только что лайкнули)
+	return c.Status(fiber.StatusOK).JSON(toCardResponse(updatedCard, true, user.ID))
 }
 
 // UnlikeCard удаляет лайк с карточки
@@ -613,8 +552,8 @@ func UnlikeCard(c *fiber.Ctx) error {
 	}
 
 	// Проверяем существование карточки
-	_, err := db.GetCardByID(cardID)
-	if err != nil {
+	card, err := db.GetCardByID(cardID)
+	if err != nil || !canViewCard(card, user.ID) {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "карточка не найдена",
 		})
@@ -636,27 +575,233 @@ func UnlikeCard(c *fiber.Ctx) error {
 		})
 	}
 
-	// Формируем ответ
-	cardResponse := models.CardResponse{
-		ID:          updatedCard.ID,
-		UserID:      updatedCard.UserID,
-		UserName:    updatedCard.UserName,
-		Image:       updatedCard.Image,
-		Title:       updatedCard.Title,
-		Description: updatedCard.Description,
-		Text:        updatedCard.Text,
-		Likes:       updatedCard.Likes,
-		IsLiked:     false, // Мы только что убрали лайк
-		CreatedAt:   updatedCard.CreatedAt,
-	}
-
-	return c.Status(fiber.StatusOK).JSON(cardResponse)
+	// Формируем ответ (мы только что убрали лайк)
+	return c.Status(fiber.StatusOK).JSON(toCardResponse(updatedCard, false, user.ID))
+}
+
+// PublishCard публикует карточку сразу или планирует публикацию на указанное время
+func PublishCard(c *fiber.Ctx) error {
+	// Получаем пользователя из локального хранилища (установленного middleware)
+	user, ok := c.Locals("user").(*models.User)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "пользователь не найден",
+		})
+	}
+
+	// Получаем ID карточки из URL
+	cardID := c.Params("cardId")
+	if cardID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "ID карточки не указан",
+		})
+	}
+
+	// Получаем карточку из базы данных
+	card, err := db.GetCardByID(cardID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "карточка не найдена",
+		})
+	}
+
+	// Проверяем, что карточка принадлежит текущему пользователю
+	if card.UserID != user.ID {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "нет прав на публикацию этой карточки",
+		})
+	}
+
+	// Парсим время отложенной публикации (необязательно)
+	var publishData struct {
+		PublishAt string `json:"publish_at" form:"publish_at"`
+	}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&publishData); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "неверный формат данных",
+			})
+		}
+	}
+
+	// Если время публикации в будущем, карточка становится запланированной
+	status := models.CardStatusPublished
+	if publishData.PublishAt != "" {
+		status = ""
+	}
+	status, publishAt, err := parseCardStatus(status, publishData.PublishAt)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if err := db.SetCardStatus(cardID, status, publishAt); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "ошибка публикации карточки",
+		})
+	}
+
+	return respondWithCard(c, cardID, user.ID)
+}
+
+// UnpublishCard снимает карточку с публикации и возвращает ее в черновики
+func UnpublishCard(c *fiber.Ctx) error {
+	// Получаем пользователя из локального хранилища (установленного middleware)
+	user, ok := c.Locals("user").(*models.User)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "пользователь не найден",
+		})
+	}
+
+	// Получаем ID карточки из URL
+	cardID := c.Params("cardId")
+	if cardID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "ID карточки не указан",
+		})
+	}
+
+	// Получаем карточку из базы данных
+	card, err := db.GetCardByID(cardID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "карточка не найдена",
+		})
+	}
+
+	// Проверяем, что карточка принадлежит текущему пользователю
+	if card.UserID != user.ID {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "нет прав на снятие этой карточки с публикации",
+		})
+	}
+
+	if err := db.SetCardStatus(cardID, models.CardStatusDraft, nil); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "ошибка снятия карточки с публикации",
+		})
+	}
+
+	return respondWithCard(c, cardID, user.ID)
+}
+
+// PreviewCard преобразует Markdown-текст карточки в HTML без сохранения
+func PreviewCard(c *fiber.Ctx) error {
+	// Парсим текст из тела запроса
+	var preview models.CardPreview
+	if err := c.BodyParser(&preview); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "неверный формат данных",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.CardPreviewResponse{
+		TextHTML: utils.RenderMarkdown(preview.Text),
+	})
 }
 
-// Вспомогательная функция для формирования URL изображения
-func getImageURL(baseURL, imagePath string) string {
-	if imagePath == "" {
-		return ""
+// respondWithCard перечитывает карточку из базы данных и отправляет ее в ответе
+func respondWithCard(c *fiber.Ctx, cardID, currentUserID string) error {
+	updatedCard, err := db.GetCardByID(cardID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "ошибка получения обновленной карточки",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(toCardResponse(updatedCard, isLikedBy(updatedCard, currentUserID), currentUserID))
+}
+
+// toCardResponse формирует ответ с карточкой, дополняя его цитируемой карточкой,
+// видимой текущему пользователю
+func toCardResponse(card *models.Card, isLiked bool, currentUserID string) models.CardResponse {
+	response := db.ToCardResponse(card, isLiked)
+
+	quote, err := db.ToQuotedCard(card.QuoteCardID, currentUserID)
+	if err != nil {
+		log.Printf("Ошибка получения цитируемой карточки %s: %v", card.QuoteCardID, err)
+		quote = &models.QuotedCard{ID: card.QuoteCardID}
+	}
+	response.Quote = quote
+
+	return response
+}
+
+// parseCardStatus проверяет статус публикации и время отложенной публикации.
+// Если статус не указан, карточка публикуется сразу либо планируется, когда задано время.
+func parseCardStatus(status, publishAtValue string) (string, *time.Time, error) {
+	var publishAt *time.Time
+	if publishAtValue != "" {
+		t, err := time.Parse(time.RFC3339, publishAtValue)
+		if err != nil {
+			return "", nil, errors.New("неверный формат времени публикации, ожидается RFC3339")
+		}
+		t = t.UTC()
+		publishAt = &t
+	}
+
+	if status == "" {
+		status = models.CardStatusPublished
+		if publishAt != nil && publishAt.After(time.Now()) {
+			status = models.CardStatusScheduled
+		}
+	}
+
+	switch status {
+	case models.CardStatusPublished:
+		if publishAt == nil || publishAt.After(time.Now()) {
+			now := time.Now().UTC()
+			publishAt = &now
+		}
+	case models.CardStatusScheduled:
+		if publishAt == nil {
+			return "", nil, errors.New("для запланированной карточки требуется время публикации")
+		}
+		if !publishAt.After(time.Now()) {
+			return "", nil, errors.New("время публикации должно быть в будущем")
+		}
+	case models.CardStatusDraft, models.CardStatusArchived:
+		publishAt = nil
+	default:
+		return "", nil, fmt.Errorf("неизвестный статус карточки: %s", status)
+	}
+
+	return status, publishAt, nil
+}
+
+// parseCardVisibility проверяет уровень видимости карточки
+func parseCardVisibility(visibility string) (string, error) {
+	switch visibility {
+	case models.CardVisibilityPublic, models.CardVisibilityUnlisted,
+		models.CardVisibilityFollowers, models.CardVisibilityPrivate:
+		return visibility, nil
+	default:
+		return "", fmt.Errorf("неизвестный уровень видимости карточки: %s", visibility)
+	}
+}
+
+// canViewCard проверяет, может ли пользователь видеть карточку.
+// Невидимые карточки обрабатываются так же, как несуществующие.
+func canViewCard(card *models.Card, userID string) bool {
+	visible, err := db.CanViewCard(card, userID)
+	if err != nil {
+		log.Printf("Ошибка проверки видимости карточки %s: %v", card.ID, err)
+		return false
+	}
+	return visible
+}
+
+// isLikedBy проверяет, лайкнул ли пользователь карточку
+func isLikedBy(card *models.Card, userID string) bool {
+	if userID == "" {
+		return false
+	}
+	for _, likedBy := range card.LikedBy {
+		if likedBy == userID {
+			return true
+		}
 	}
-	return baseURL + imagePath
+	return false
 }
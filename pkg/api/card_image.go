@@ -0,0 +1,309 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"mime/multipart"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/user/roma/pkg/db"
+	"github.com/user/roma/pkg/models"
+	"github.com/user/roma/pkg/utils"
+)
+
+// cardImageUploads представляет изображения, загруженные вместе с карточкой
+type cardImageUploads struct {
+	Cover  *models.CardImage  // поле image: новая обложка
+	Images []models.CardImage // поле images: изображения для галереи
+	saved  []string           // сохраненные файлы, по одной ссылке на каждое сохранение
+	used   []string           // использованные возобновляемые загрузки
+}
+
+// Count возвращает количество загруженных изображений
+func (u cardImageUploads) Count() int {
+	if u.Cover != nil {
+		return len(u.Images) + 1
+	}
+	return len(u.Images)
+}
+
+// All возвращает все загруженные изображения, начиная с обложки
+func (u cardImageUploads) All() []models.CardImage {
+	images := []models.CardImage{}
+	if u.Cover != nil {
+		images = append(images, *u.Cover)
+	}
+	return append(images, u.Images...)
+}
+
+// Remove удаляет сохраненные файлы, если запрос завершился ошибкой.
+// Возобновляемые загрузки остаются, и их можно указать в повторном запросе.
+func (u cardImageUploads) Remove() {
+	for _, filename := range u.saved {
+		if err := utils.RemoveImage(filename); err != nil {
+			log.Printf("Ошибка при удалении изображения: %v", err)
+		}
+	}
+}
+
+// Commit оставляет карточке по одной ссылке на каждый файл после успешного сохранения.
+// Одинаковые изображения хранятся одним файлом, а при удалении карточки ссылка на каждый
+// ее файл убирается один раз, поэтому лишние ссылки на файлы, которые уже есть
+// у карточки (existing) или загружены в запросе несколько раз, убираются сразу.
+// Использованные возобновляемые загрузки удаляются.
+func (u cardImageUploads) Commit(existing []string) {
+	finishUploads(u.used...)
+
+	seen := map[string]bool{}
+	for _, filename := range existing {
+		seen[filename] = true
+	}
+	for _, filename := range u.saved {
+		if !seen[filename] {
+			seen[filename] = true
+			continue
+		}
+		if err := utils.RemoveImage(filename); err != nil {
+			log.Printf("Ошибка при удалении ссылки на изображение: %v", err)
+		}
+	}
+}
+
+// saveCardImageUploads сохраняет изображения из формы.
+// Поле image содержит обложку (как раньше), поле images — изображения галереи,
+// а поля captions и alts — подписи и альтернативный текст в том же порядке.
+// Изображения передаются файлами multipart-формы, строками base64 (data URL)
+// в тех же полях или ID завершенных возобновляемых загрузок в полях image_upload_id
+// и images_upload_id; файлы идут раньше строк, строки — раньше загрузок.
+func saveCardImageUploads(c *fiber.Ctx) (cardImageUploads, error) {
+	uploads := cardImageUploads{}
+
+	files, values, ok := cardForm(c)
+	if !ok {
+		// Запрос без формы не содержит изображений
+		return uploads, nil
+	}
+
+	// Собираем источники изображений: сначала обложку, затем галерею
+	sources := []cardImageSource{}
+	for _, field := range []string{"image", "images"} {
+		for _, file := range files[field] {
+			sources = append(sources, cardImageSource{file: file})
+		}
+		for _, value := range values[field] {
+			if value != "" {
+				sources = append(sources, cardImageSource{base64: value})
+			}
+		}
+		for _, uploadID := range values[field+"_upload_id"] {
+			if uploadID != "" {
+				sources = append(sources, cardImageSource{uploadID: uploadID})
+			}
+		}
+		// Обложка может быть только одна
+		if field == "image" && len(sources) > 1 {
+			sources = sources[:1]
+		}
+	}
+	hasCover := len(files["image"]) > 0 || hasNonEmpty(values["image"]) || hasNonEmpty(values["image_upload_id"])
+
+	if len(sources) > utils.MaxCardImages {
+		return uploads, fiber.NewError(fiber.StatusBadRequest,
+			fmt.Sprintf("карточка может содержать не более %d изображений", utils.MaxCardImages))
+	}
+
+	userID := ""
+	if user, ok := c.Locals("user").(*models.User); ok {
+		userID = user.ID
+	}
+
+	captions := values["captions"]
+	alts := values["alts"]
+	for i, source := range sources {
+		filename, err := source.save(userID)
+		if err != nil {
+			uploads.Remove()
+			return cardImageUploads{}, err
+		}
+		uploads.saved = append(uploads.saved, filename)
+		if source.uploadID != "" {
+			uploads.used = append(uploads.used, source.uploadID)
+		}
+
+		image := models.CardImage{Filename: filename}
+		if i < len(captions) {
+			image.Caption = captions[i]
+		}
+		if i < len(alts) {
+			image.Alt = alts[i]
+		}
+
+		if i == 0 && hasCover {
+			uploads.Cover = &image
+		} else {
+			uploads.Images = append(uploads.Images, image)
+		}
+	}
+
+	return uploads, nil
+}
+
+// cardForm возвращает файлы и значения multipart-формы или значения обычной формы
+func cardForm(c *fiber.Ctx) (map[string][]*multipart.FileHeader, map[string][]string, bool) {
+	if form, err := c.MultipartForm(); err == nil {
+		return form.File, form.Value, true
+	}
+	if args := c.Request().PostArgs(); args.Len() > 0 {
+		values := map[string][]string{}
+		args.VisitAll(func(key, value []byte) {
+			values[string(key)] = append(values[string(key)], string(value))
+		})
+		return nil, values, true
+	}
+	return nil, nil, false
+}
+
+// cardImageTexts возвращает подписи и альтернативный текст изображений из формы
+// для проверки правилами содержимого
+func cardImageTexts(c *fiber.Ctx) []string {
+	_, values, _ := cardForm(c)
+	return append(append([]string{}, values["captions"]...), values["alts"]...)
+}
+
+// cardImageSource — изображение карточки из файла формы, строки base64 или возобновляемой загрузки
+type cardImageSource struct {
+	file     *multipart.FileHeader
+	base64   string
+	uploadID string
+}
+
+// save сохраняет изображение карточки; загрузка должна принадлежать пользователю userID
+func (s cardImageSource) save(userID string) (string, error) {
+	switch {
+	case s.file != nil:
+		return saveMultipartImage(s.file, utils.CardImage)
+	case s.uploadID != "":
+		return saveUploadedImage(userID, s.uploadID, utils.CardImage)
+	default:
+		return saveBase64Image(s.base64, utils.CardImage)
+	}
+}
+
+// hasNonEmpty проверяет, есть ли среди значений непустое
+func hasNonEmpty(values []string) bool {
+	for _, value := range values {
+		if value != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// errorResponse отправляет ошибку с кодом из *fiber.Error или 500 для остальных ошибок
+func errorResponse(c *fiber.Ctx, err error) error {
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		return c.Status(fiberErr.Code).JSON(fiber.Map{
+			"error": fiberErr.Message,
+		})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": err.Error(),
+	})
+}
+
+// ReorderCardImages задает новый порядок изображений галереи карточки
+func ReorderCardImages(c *fiber.Ctx) error {
+	// Получаем пользователя из локального хранилища (установленного middleware)
+	user, ok := c.Locals("user").(*models.User)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "пользователь не найден",
+		})
+	}
+
+	// Получаем ID карточки из URL
+	cardID := c.Params("cardId")
+	if cardID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "ID карточки не указан",
+		})
+	}
+
+	// Получаем карточку из базы данных
+	card, err := db.GetCardByID(cardID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "карточка не найдена",
+		})
+	}
+
+	// Проверяем, что карточка принадлежит текущему пользователю
+	if card.UserID != user.ID {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "нет прав на редактирование этой карточки",
+		})
+	}
+
+	// Парсим новый порядок изображений
+	var order models.CardImagesOrder
+	if err := c.BodyParser(&order); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "неверный формат данных",
+		})
+	}
+
+	if err := db.ReorderCardImages(cardID, order.ImageIDs); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return respondWithCard(c, cardID, user.ID)
+}
+
+// RemoveCardImage удаляет изображение из галереи карточки
+func RemoveCardImage(c *fiber.Ctx) error {
+	// Получаем пользователя из локального хранилища (установленного middleware)
+	user, ok := c.Locals("user").(*models.User)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "пользователь не найден",
+		})
+	}
+
+	// Получаем ID карточки и изображения из URL
+	cardID := c.Params("cardId")
+	imageID := c.Params("imageId")
+	if cardID == "" || imageID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "ID карточки или изображения не указан",
+		})
+	}
+
+	// Получаем карточку из базы данных
+	card, err := db.GetCardByID(cardID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "карточка не найдена",
+		})
+	}
+
+	// Проверяем, что карточка принадлежит текущему пользователю
+	if card.UserID != user.ID {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "нет прав на редактирование этой карточки",
+		})
+	}
+
+	// Файл остается на диске до окончательного удаления карточки,
+	// так как на него может ссылаться история версий
+	if err := db.RemoveCardImage(cardID, imageID); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return respondWithCard(c, cardID, user.ID)
+}
@@ -0,0 +1,29 @@
+package api
+
+import (
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/user/roma/pkg/contentcheck"
+	"github.com/user/roma/pkg/db"
+)
+
+// contentRejected отвечает на запрос, отклоненный проверкой содержимого,
+// перечисляя сработавшие правила
+func contentRejected(c *fiber.Ctx, result contentcheck.Result) error {
+	return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+		"error":      "текст не прошел проверку содержимого",
+		"violations": result.Violations,
+	})
+}
+
+// holdCardIfNeeded скрывает сохраненную карточку до проверки модератором,
+// если этого требует результат проверки содержимого
+func holdCardIfNeeded(cardID string, result contentcheck.Result) {
+	if result.Action != contentcheck.ActionHold {
+		return
+	}
+	if err := db.HoldCardForReview(cardID, result.Summary()); err != nil {
+		log.Printf("Ошибка отправки карточки %s на проверку: %v", cardID, err)
+	}
+}
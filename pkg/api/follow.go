@@ -0,0 +1,74 @@
+package api
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/user/roma/pkg/db"
+	"github.com/user/roma/pkg/models"
+)
+
+// FollowUser подписывает текущего пользователя на другого пользователя
+func FollowUser(c *fiber.Ctx) error {
+	// Получаем пользователя из локального хранилища (установленного middleware)
+	user, ok := c.Locals("user").(*models.User)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "пользователь не найден",
+		})
+	}
+
+	// Получаем ID пользователя из URL
+	userID := c.Params("userId")
+	if userID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "ID пользователя не указан",
+		})
+	}
+
+	// Проверяем существование пользователя
+	if _, err := db.GetUserByID(userID); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "пользователь не найден",
+		})
+	}
+
+	// Добавляем подписку
+	if err := db.FollowUser(user.ID, userID); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "подписка оформлена",
+	})
+}
+
+// UnfollowUser отменяет подписку текущего пользователя
+func UnfollowUser(c *fiber.Ctx) error {
+	// Получаем пользователя из локального хранилища (установленного middleware)
+	user, ok := c.Locals("user").(*models.User)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "пользователь не найден",
+		})
+	}
+
+	// Получаем ID пользователя из URL
+	userID := c.Params("userId")
+	if userID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "ID пользователя не указан",
+		})
+	}
+
+	// Удаляем подписку
+	if err := db.UnfollowUser(user.ID, userID); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "подписка отменена",
+	})
+}
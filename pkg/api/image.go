@@ -0,0 +1,120 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/user/roma/pkg/utils"
+)
+
+// TransformImage отдает изображение из загрузок с измененным размером или форматом.
+// Параметры преобразования должны быть подписаны сервером (параметр sig).
+func TransformImage(c *fiber.Ctx) error {
+	filename := c.Params("filename")
+
+	transform, err := utils.ParseImageTransform(c.Query("w"), c.Query("h"), c.Query("fit"), c.Query("format"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if !utils.VerifyImageTransform(filename, transform, c.Query("sig")) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "неверная подпись ссылки",
+		})
+	}
+
+	path, key, err := utils.TransformedImage(filename, transform)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "изображение не найдено",
+			})
+		}
+		log.Printf("Ошибка преобразования изображения: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "ошибка преобразования изображения",
+		})
+	}
+
+	// Клиентам с поддержкой WebP отдаем копию в WebP, если она есть (она создается, только когда меньше)
+	etag := `"` + key + `"`
+	c.Vary(fiber.HeaderAccept)
+	if acceptsWebP(c) {
+		if _, err := os.Stat(utils.WebPAlternative(path)); err == nil {
+			path = utils.WebPAlternative(path)
+			etag = `"` + key + `-webp"`
+		}
+	}
+
+	// Имена загруженных файлов уникальны, поэтому результат преобразования не меняется
+	c.Set(fiber.HeaderCacheControl, "public, max-age=31536000, immutable")
+	c.Set(fiber.HeaderETag, etag)
+	if c.Get(fiber.HeaderIfNoneMatch) == etag {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+
+	return c.SendFile(path)
+}
+
+// ServeUpload отдает загруженное изображение из хранилища. Клиентам с поддержкой WebP
+// отдается копия в WebP, если она есть.
+func ServeUpload(c *fiber.Ctx) error {
+	c.Vary(fiber.HeaderAccept)
+
+	reader, name, err := utils.OpenImageForClient(c.Params("filename"), acceptsWebP(c))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "изображение не найдено",
+			})
+		}
+		log.Printf("Ошибка чтения изображения: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "ошибка чтения изображения",
+		})
+	}
+
+	// Имя файла определяется его содержимым, поэтому файл по этому адресу никогда не меняется
+	etag := `"` + name + `"`
+	c.Set(fiber.HeaderCacheControl, "public, max-age=31536000, immutable")
+	c.Set(fiber.HeaderETag, etag)
+	if c.Get(fiber.HeaderIfNoneMatch) == etag {
+		reader.Close()
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+
+	c.Type(strings.TrimPrefix(filepath.Ext(name), "."))
+	return c.SendStream(reader)
+}
+
+// acceptsWebP проверяет, поддерживает ли клиент WebP, по заголовку Accept
+func acceptsWebP(c *fiber.Ctx) bool {
+	return strings.Contains(c.Get(fiber.HeaderAccept), "image/webp")
+}
+
+// GetImageTransformURL возвращает подписанную ссылку на преобразованное изображение
+func GetImageTransformURL(c *fiber.Ctx) error {
+	filename := c.Params("filename")
+	if !utils.ImageExists(filename) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "изображение не найдено",
+		})
+	}
+
+	transform, err := utils.ParseImageTransform(c.Query("w"), c.Query("h"), c.Query("fit"), c.Query("format"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"url": utils.TransformURL(utils.TransformBaseURL, filename, transform),
+	})
+}
@@ -0,0 +1,123 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log"
+	"mime/multipart"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/user/roma/pkg/models"
+	"github.com/user/roma/pkg/utils"
+)
+
+// errNoImage возвращается, когда запрос не содержит изображения
+var errNoImage = fiber.NewError(fiber.StatusBadRequest, "изображение не передано")
+
+// saveImageUpload сохраняет изображение из запроса. Изображение принимается как файл
+// multipart-формы в поле field, как строка base64 или data URL в поле формы или JSON
+// с тем же именем, как ID завершенной возобновляемой загрузки в поле field_upload_id,
+// либо как тело запроса с типом image/*. Возвращает также ID использованной загрузки,
+// которую нужно удалить через finishUploads после успешного запроса.
+func saveImageUpload(c *fiber.Ctx, field string, kind utils.ImageKind) (string, string, error) {
+	contentType := c.Get(fiber.HeaderContentType)
+	uploadField := field + "_upload_id"
+
+	switch {
+	case strings.HasPrefix(contentType, fiber.MIMEMultipartForm):
+		if file, err := c.FormFile(field); err == nil {
+			filename, err := saveMultipartImage(file, kind)
+			return filename, "", err
+		}
+		if uploadID := c.FormValue(uploadField); uploadID != "" {
+			filename, err := saveUploadedImageFromRequest(c, uploadID, kind)
+			return filename, uploadID, err
+		}
+		filename, err := saveBase64Image(c.FormValue(field), kind)
+		return filename, "", err
+
+	case strings.HasPrefix(contentType, fiber.MIMEApplicationForm):
+		if uploadID := c.FormValue(uploadField); uploadID != "" {
+			filename, err := saveUploadedImageFromRequest(c, uploadID, kind)
+			return filename, uploadID, err
+		}
+		filename, err := saveBase64Image(c.FormValue(field), kind)
+		return filename, "", err
+
+	case strings.HasPrefix(contentType, fiber.MIMEApplicationJSON):
+		var body map[string]any
+		if err := json.Unmarshal(c.Body(), &body); err != nil {
+			return "", "", fiber.NewError(fiber.StatusBadRequest, "неверный формат данных")
+		}
+		if uploadID, _ := body[uploadField].(string); uploadID != "" {
+			filename, err := saveUploadedImageFromRequest(c, uploadID, kind)
+			return filename, uploadID, err
+		}
+		value, _ := body[field].(string)
+		filename, err := saveBase64Image(value, kind)
+		return filename, "", err
+
+	case strings.HasPrefix(contentType, "image/"):
+		if len(c.Body()) == 0 {
+			return "", "", errNoImage
+		}
+		filename, err := utils.SaveImage(bytes.NewReader(c.Body()), kind)
+		return filename, "", imageUploadError(err)
+	}
+
+	return "", "", errNoImage
+}
+
+// saveMultipartImage сохраняет изображение из файла multipart-формы
+func saveMultipartImage(file *multipart.FileHeader, kind utils.ImageKind) (string, error) {
+	// Проверяем размер файла до чтения
+	if file.Size > utils.MaxImageSize {
+		return "", fiber.NewError(fiber.StatusBadRequest, "размер изображения превышает максимально допустимый")
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		log.Printf("Ошибка при открытии загруженного файла: %v", err)
+		return "", fiber.NewError(fiber.StatusInternalServerError, "ошибка при обработке файла")
+	}
+	defer src.Close()
+
+	filename, err := utils.SaveImage(src, kind)
+	return filename, imageUploadError(err)
+}
+
+// saveUploadedImageFromRequest сохраняет изображение из возобновляемой загрузки текущего пользователя
+func saveUploadedImageFromRequest(c *fiber.Ctx, uploadID string, kind utils.ImageKind) (string, error) {
+	user, ok := c.Locals("user").(*models.User)
+	if !ok {
+		return "", fiber.NewError(fiber.StatusUnauthorized, "пользователь не найден")
+	}
+	return saveUploadedImage(user.ID, uploadID, kind)
+}
+
+// saveBase64Image сохраняет изображение из строки base64 или data URL
+func saveBase64Image(value string, kind utils.ImageKind) (string, error) {
+	if value == "" {
+		return "", errNoImage
+	}
+	filename, err := utils.SaveImageFromBase64(value, kind)
+	return filename, imageUploadError(err)
+}
+
+// imageUploadError превращает ошибку сохранения изображения в ответ: ошибки в самом
+// изображении возвращаются клиенту с кодом 400, остальные записываются в лог
+func imageUploadError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var imageErr *utils.ImageError
+	if errors.As(err, &imageErr) {
+		return fiber.NewError(fiber.StatusBadRequest, imageErr.Message)
+	}
+
+	log.Printf("Ошибка при сохранении изображения: %v", err)
+	return fiber.NewError(fiber.StatusInternalServerError, "ошибка при сохранении файла")
+}
@@ -0,0 +1,302 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/user/roma/pkg/db"
+	"github.com/user/roma/pkg/models"
+	"github.com/user/roma/pkg/utils"
+)
+
+// GetReports возвращает очередь модерации: жалобы, начиная с самых старых
+func GetReports(c *fiber.Ctx) error {
+	// Получаем фильтр по статусу (по умолчанию открытые и взятые в работу жалобы)
+	status := c.Query("status")
+	switch status {
+	case "", models.ReportStatusOpen, models.ReportStatusClaimed, models.ReportStatusResolved:
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "неизвестный статус жалобы",
+		})
+	}
+
+	// Получаем параметры пагинации из запроса
+	page, err := strconv.Atoi(c.Query("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	limit, err := strconv.Atoi(c.Query("limit", "20"))
+	if err != nil || limit < 1 {
+		limit = 20
+	}
+
+	response, err := db.GetReports(status, page, limit)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "ошибка получения жалоб",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(response)
+}
+
+// GetReport возвращает жалобу по ID
+func GetReport(c *fiber.Ctx) error {
+	report, err := db.GetReportByID(c.Params("reportId"))
+	if err != nil {
+		return reportErrorResponse(c, err, "ошибка получения жалобы")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(report)
+}
+
+// ClaimReport берет жалобу в работу текущего модератора
+func ClaimReport(c *fiber.Ctx) error {
+	// Получаем пользователя из локального хранилища (установленного middleware)
+	user, ok := c.Locals("user").(*models.User)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "пользователь не найден",
+		})
+	}
+
+	reportID := c.Params("reportId")
+	if _, err := db.GetReportByID(reportID); err != nil {
+		return reportErrorResponse(c, err, "ошибка получения жалобы")
+	}
+
+	if err := db.ClaimReport(reportID, user.ID); err != nil {
+		return reportErrorResponse(c, err, "ошибка взятия жалобы в работу")
+	}
+
+	return respondWithReport(c, reportID)
+}
+
+// ResolveReport принимает решение по жалобе: отклонить, одобрить скрытую карточку,
+// скрыть или удалить карточку, заблокировать автора
+func ResolveReport(c *fiber.Ctx) error {
+	// Получаем пользователя из локального хранилища (установленного middleware)
+	user, ok := c.Locals("user").(*models.User)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "пользователь не найден",
+		})
+	}
+
+	reportID := c.Params("reportId")
+	if _, err := db.GetReportByID(reportID); err != nil {
+		return reportErrorResponse(c, err, "ошибка получения жалобы")
+	}
+
+	// Парсим решение модератора
+	var resolve models.ReportResolve
+	if err := c.BodyParser(&resolve); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "неверный формат данных",
+		})
+	}
+
+	// Удаленная карточка удаляется из базы вместе с решением, ее изображения — после него
+	files, err := db.ResolveReport(reportID, user.ID, resolve)
+	if err != nil {
+		return reportErrorResponse(c, err, "ошибка решения по жалобе")
+	}
+	removeImages(files)
+
+	return respondWithReport(c, reportID)
+}
+
+// GetModerationLog возвращает журнал решений модераторов
+func GetModerationLog(c *fiber.Ctx) error {
+	// Получаем параметры пагинации из запроса
+	page, err := strconv.Atoi(c.Query("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	limit, err := strconv.Atoi(c.Query("limit", "20"))
+	if err != nil || limit < 1 {
+		limit = 20
+	}
+
+	response, err := db.GetModerationLog(page, limit)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "ошибка получения журнала модерации",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(response)
+}
+
+// respondWithReport перечитывает жалобу из базы данных и отправляет ее в ответе
+func respondWithReport(c *fiber.Ctx, reportID string) error {
+	report, err := db.GetReportByID(reportID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "ошибка получения обновленной жалобы",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(report)
+}
+
+// reportErrorResponse отправляет ошибку работы с жалобой: отсутствующая жалоба — 404,
+// конфликт с состоянием жалобы — 409, неприменимое решение — 400. Остальные ошибки
+// записываются в лог, а клиент получает message.
+func reportErrorResponse(c *fiber.Ctx, err error, message string) error {
+	var reportErr *db.ReportError
+	status := fiber.StatusInternalServerError
+	switch {
+	case errors.Is(err, db.ErrReportNotFound):
+		status = fiber.StatusNotFound
+	case errors.Is(err, db.ErrReportConflict), errors.Is(err, db.ErrReportNotClaimed), errors.Is(err, db.ErrReportExists):
+		status = fiber.StatusConflict
+	case errors.As(err, &reportErr):
+		status = fiber.StatusBadRequest
+	default:
+		log.Printf("%s: %v", message, err)
+		return c.Status(status).JSON(fiber.Map{
+			"error": message,
+		})
+	}
+
+	return c.Status(status).JSON(fiber.Map{
+		"error": err.Error(),
+	})
+}
+
+// removeImages убирает ссылки на изображения окончательно удаленной карточки
+func removeImages(images []string) {
+	for _, image := range images {
+		if err := utils.RemoveImage(image); err != nil && !os.IsNotExist(err) {
+			log.Printf("Ошибка при удалении изображения %s: %v", image, err)
+		}
+	}
+}
+
+// SuspendUser блокирует пользователя: его токены отклоняются, а карточки скрываются
+func SuspendUser(c *fiber.Ctx) error {
+	admin, target, err := moderationTarget(c)
+	if err != nil {
+		return errorResponse(c, err)
+	}
+
+	// Парсим причину и срок блокировки
+	var suspend models.UserSuspend
+	if err := c.BodyParser(&suspend); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "неверный формат данных",
+		})
+	}
+
+	if suspend.Reason == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "причина блокировки обязательна",
+		})
+	}
+	if suspend.Until != nil && !suspend.Until.After(time.Now()) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "срок блокировки должен быть в будущем",
+		})
+	}
+
+	if err := db.SuspendUser(admin.ID, target.ID, suspend); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "ошибка блокировки пользователя",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "пользователь заблокирован",
+	})
+}
+
+// UnsuspendUser снимает блокировку пользователя
+func UnsuspendUser(c *fiber.Ctx) error {
+	admin, target, err := moderationTarget(c)
+	if err != nil {
+		return errorResponse(c, err)
+	}
+
+	if err := db.LiftSuspension(admin.ID, target.ID, moderationNote(c)); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "блокировка снята",
+	})
+}
+
+// ShadowBanUser скрывает карточки пользователя от всех, кроме него самого
+func ShadowBanUser(c *fiber.Ctx) error {
+	admin, target, err := moderationTarget(c)
+	if err != nil {
+		return errorResponse(c, err)
+	}
+
+	if err := db.ShadowBanUser(admin.ID, target.ID, moderationNote(c)); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "контент пользователя скрыт",
+	})
+}
+
+// UnshadowBanUser снова делает карточки пользователя видимыми
+func UnshadowBanUser(c *fiber.Ctx) error {
+	admin, target, err := moderationTarget(c)
+	if err != nil {
+		return errorResponse(c, err)
+	}
+
+	if err := db.LiftShadowBan(admin.ID, target.ID, moderationNote(c)); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "контент пользователя снова виден",
+	})
+}
+
+// moderationTarget получает администратора и пользователя, к которому применяется действие.
+// Действия с собой и с другими администраторами запрещены.
+func moderationTarget(c *fiber.Ctx) (*models.User, *models.User, error) {
+	admin, ok := c.Locals("user").(*models.User)
+	if !ok {
+		return nil, nil, fiber.NewError(fiber.StatusUnauthorized, "пользователь не найден")
+	}
+
+	target, err := db.GetUserByID(c.Params("userId"))
+	if err != nil {
+		return nil, nil, fiber.NewError(fiber.StatusNotFound, "пользователь не найден")
+	}
+
+	if target.ID == admin.ID || target.IsAdmin() {
+		return nil, nil, fiber.NewError(fiber.StatusBadRequest, "действие нельзя применить к администратору")
+	}
+
+	return admin, target, nil
+}
+
+// moderationNote получает необязательный комментарий администратора из тела запроса
+func moderationNote(c *fiber.Ctx) string {
+	var note models.ModerationNote
+	if len(c.Body()) > 0 {
+		_ = c.BodyParser(&note)
+	}
+	return note.Note
+}
@@ -0,0 +1,120 @@
+package api
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/user/roma/pkg/db"
+	"github.com/user/roma/pkg/models"
+)
+
+// PinCard закрепляет карточку в профиле текущего пользователя
+func PinCard(c *fiber.Ctx) error {
+	// Получаем пользователя из локального хранилища (установленного middleware)
+	user, ok := c.Locals("user").(*models.User)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "пользователь не найден",
+		})
+	}
+
+	// Получаем ID карточки из URL
+	cardID := c.Params("cardId")
+	if cardID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "ID карточки не указан",
+		})
+	}
+
+	// Получаем карточку из базы данных
+	card, err := db.GetCardByID(cardID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "карточка не найдена",
+		})
+	}
+
+	// Закреплять можно только свои карточки
+	if card.UserID != user.ID {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "нет прав на закрепление этой карточки",
+		})
+	}
+
+	if err := db.PinCard(cardID, user.ID); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return respondWithCard(c, cardID, user.ID)
+}
+
+// UnpinCard открепляет карточку из профиля текущего пользователя
+func UnpinCard(c *fiber.Ctx) error {
+	// Получаем пользователя из локального хранилища (установленного middleware)
+	user, ok := c.Locals("user").(*models.User)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "пользователь не найден",
+		})
+	}
+
+	// Получаем ID карточки из URL
+	cardID := c.Params("cardId")
+	if cardID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "ID карточки не указан",
+		})
+	}
+
+	// Получаем карточку из базы данных
+	card, err := db.GetCardByID(cardID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "карточка не найдена",
+		})
+	}
+
+	// Откреплять можно только свои карточки
+	if card.UserID != user.ID {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "нет прав на открепление этой карточки",
+		})
+	}
+
+	if err := db.UnpinCard(cardID, user.ID); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return respondWithCard(c, cardID, user.ID)
+}
+
+// ReorderPinnedCards задает новый порядок закрепленных карточек текущего пользователя
+func ReorderPinnedCards(c *fiber.Ctx) error {
+	// Получаем пользователя из локального хранилища (установленного middleware)
+	user, ok := c.Locals("user").(*models.User)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "пользователь не найден",
+		})
+	}
+
+	// Парсим новый порядок закрепленных карточек
+	var order models.PinnedCardsOrder
+	if err := c.BodyParser(&order); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "неверный формат данных",
+		})
+	}
+
+	if err := db.ReorderPinnedCards(user.ID, order.CardIDs); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "порядок закрепленных карточек обновлен",
+	})
+}
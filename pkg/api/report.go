@@ -0,0 +1,89 @@
+package api
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/user/roma/pkg/contentcheck"
+	"github.com/user/roma/pkg/db"
+	"github.com/user/roma/pkg/models"
+)
+
+// ReportCard отправляет жалобу на карточку
+func ReportCard(c *fiber.Ctx) error {
+	// Получаем пользователя из локального хранилища (установленного middleware)
+	user, ok := c.Locals("user").(*models.User)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "пользователь не найден",
+		})
+	}
+
+	// Получаем ID карточки из URL
+	cardID := c.Params("cardId")
+	if cardID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "ID карточки не указан",
+		})
+	}
+
+	// Пожаловаться можно только на видимую пользователю карточку
+	card, err := db.GetCardByID(cardID)
+	if err != nil || !canViewCard(card, user.ID) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "карточка не найдена",
+		})
+	}
+
+	if card.UserID == user.ID {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "нельзя пожаловаться на собственную карточку",
+		})
+	}
+
+	// Парсим жалобу
+	var report models.ReportCreate
+	if err := c.BodyParser(&report); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "неверный формат данных",
+		})
+	}
+
+	if !isReportReason(report.Reason) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "неизвестная причина жалобы",
+		})
+	}
+	if report.Reason == models.ReportReasonOther && report.Comment == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "для причины other требуется комментарий",
+		})
+	}
+
+	// Комментарий жалобы часто цитирует то, на что жалуются, поэтому правила проверки
+	// его не отклоняют: сработавшие правила сохраняются в жалобе для модератора
+	check := contentcheck.Check(contentcheck.Input{
+		Kind:   contentcheck.KindReport,
+		UserID: user.ID,
+		Fields: []string{report.Comment},
+	})
+
+	created, err := db.CreateReport(cardID, user.ID, report, check.Summary())
+	if err != nil {
+		return reportErrorResponse(c, err, "ошибка создания жалобы")
+	}
+
+	// Сработавшие правила видят только модераторы
+	created.ContentCheck = ""
+	return c.Status(fiber.StatusCreated).JSON(created)
+}
+
+// isReportReason проверяет причину жалобы
+func isReportReason(reason string) bool {
+	switch reason {
+	case models.ReportReasonSpam, models.ReportReasonHarassment, models.ReportReasonHate,
+		models.ReportReasonViolence, models.ReportReasonNudity, models.ReportReasonMisinformation,
+		models.ReportReasonCopyright, models.ReportReasonOther:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,93 @@
+package api
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/user/roma/pkg/db"
+	"github.com/user/roma/pkg/models"
+)
+
+// RepostCard добавляет карточку в ленту текущего пользователя
+func RepostCard(c *fiber.Ctx) error {
+	// Получаем пользователя из локального хранилища (установленного middleware)
+	user, ok := c.Locals("user").(*models.User)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "пользователь не найден",
+		})
+	}
+
+	// Получаем ID карточки из URL
+	cardID := c.Params("cardId")
+	if cardID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "ID карточки не указан",
+		})
+	}
+
+	// Проверяем существование карточки
+	card, err := db.GetCardByID(cardID)
+	if err != nil || !canViewCard(card, user.ID) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "карточка не найдена",
+		})
+	}
+
+	// Свои карточки и так есть в ленте автора
+	if card.UserID == user.ID {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "нельзя репостнуть собственную карточку",
+		})
+	}
+
+	// Репостить можно только публичные карточки, иначе репост раскрыл бы их
+	// пользователям, которым оригинал не виден
+	if card.Visibility != models.CardVisibilityPublic {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "репостнуть можно только публичную карточку",
+		})
+	}
+
+	// Добавляем репост
+	if err := db.RepostCard(cardID, user.ID); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return respondWithCard(c, cardID, user.ID)
+}
+
+// UnrepostCard отменяет репост карточки
+func UnrepostCard(c *fiber.Ctx) error {
+	// Получаем пользователя из локального хранилища (установленного middleware)
+	user, ok := c.Locals("user").(*models.User)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "пользователь не найден",
+		})
+	}
+
+	// Получаем ID карточки из URL
+	cardID := c.Params("cardId")
+	if cardID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "ID карточки не указан",
+		})
+	}
+
+	// Репост можно отменить, даже если оригинал уже удален или скрыт
+	if err := db.UnrepostCard(cardID, user.ID); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	card, err := db.GetCardByID(cardID)
+	if err != nil || !canViewCard(card, user.ID) {
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message": "репост отменен",
+		})
+	}
+
+	return respondWithCard(c, cardID, user.ID)
+}
@@ -0,0 +1,223 @@
+package api
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/user/roma/pkg/db"
+	"github.com/user/roma/pkg/models"
+	"github.com/user/roma/pkg/utils"
+)
+
+// GetCardRevisions получает историю версий карточки
+func GetCardRevisions(c *fiber.Ctx) error {
+	// Получаем пользователя из локального хранилища (установленного middleware)
+	user, ok := c.Locals("user").(*models.User)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "пользователь не найден",
+		})
+	}
+
+	// Получаем ID карточки из URL
+	cardID := c.Params("cardId")
+	if cardID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "ID карточки не указан",
+		})
+	}
+
+	// Получаем карточку из базы данных
+	card, err := db.GetCardByID(cardID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "карточка не найдена",
+		})
+	}
+
+	// История версий доступна только автору карточки
+	if card.UserID != user.ID {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "нет прав на просмотр истории этой карточки",
+		})
+	}
+
+	revisions, err := db.GetCardRevisions(cardID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "ошибка получения истории версий",
+		})
+	}
+
+	// Формируем ответ
+	response := []models.CardRevisionResponse{}
+	for _, revision := range revisions {
+		response = append(response, toRevisionResponse(revision))
+	}
+
+	return c.Status(fiber.StatusOK).JSON(response)
+}
+
+// GetCardRevisionDiff возвращает построчную разницу текста между двумя версиями карточки.
+// По умолчанию сравнивается последняя версия с предыдущей.
+func GetCardRevisionDiff(c *fiber.Ctx) error {
+	// Получаем пользователя из локального хранилища (установленного middleware)
+	user, ok := c.Locals("user").(*models.User)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "пользователь не найден",
+		})
+	}
+
+	// Получаем ID карточки из URL
+	cardID := c.Params("cardId")
+	if cardID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "ID карточки не указан",
+		})
+	}
+
+	// Получаем карточку из базы данных
+	card, err := db.GetCardByID(cardID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "карточка не найдена",
+		})
+	}
+
+	// История версий доступна только автору карточки
+	if card.UserID != user.ID {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "нет прав на просмотр истории этой карточки",
+		})
+	}
+
+	revisions, err := db.GetCardRevisions(cardID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "ошибка получения истории версий",
+		})
+	}
+	if len(revisions) == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "версия карточки не найдена",
+		})
+	}
+
+	// Версии отсортированы от последней к первой
+	to := c.QueryInt("to", revisions[0].Revision)
+	from := c.QueryInt("from", to-1)
+
+	fromRevision, err := findRevision(revisions, from)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	toRevision, err := findRevision(revisions, to)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	lines, err := utils.DiffLines(fromRevision.Text, toRevision.Text)
+	if err != nil {
+		return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.RevisionDiffResponse{
+		From:  fromRevision.Revision,
+		To:    toRevision.Revision,
+		Lines: lines,
+	})
+}
+
+// RestoreCardRevision возвращает карточку к указанной версии, включая изображение
+func RestoreCardRevision(c *fiber.Ctx) error {
+	// Получаем пользователя из локального хранилища (установленного middleware)
+	user, ok := c.Locals("user").(*models.User)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "пользователь не найден",
+		})
+	}
+
+	// Получаем ID карточки из URL
+	cardID := c.Params("cardId")
+	if cardID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "ID карточки не указан",
+		})
+	}
+
+	// Получаем номер версии из URL
+	rev, err := strconv.Atoi(c.Params("rev"))
+	if err != nil || rev < 1 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "неверный номер версии",
+		})
+	}
+
+	// Получаем карточку из базы данных
+	card, err := db.GetCardByID(cardID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "карточка не найдена",
+		})
+	}
+
+	// Проверяем, что карточка принадлежит текущему пользователю
+	if card.UserID != user.ID {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "нет прав на редактирование этой карточки",
+		})
+	}
+
+	revision, err := db.GetCardRevision(cardID, rev)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "версия карточки не найдена",
+		})
+	}
+
+	// Если файл изображения версии утерян, восстанавливаем карточку без изображения
+	if revision.Image != "" && !utils.ImageExists(revision.Image) {
+		log.Printf("Изображение версии %d карточки %s не найдено: %s", rev, cardID, revision.Image)
+		revision.Image = ""
+	}
+
+	if err := db.RestoreCardRevision(cardID, revision); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "ошибка восстановления версии карточки",
+		})
+	}
+
+	return respondWithCard(c, cardID, user.ID)
+}
+
+// findRevision ищет версию карточки по номеру
+func findRevision(revisions []models.CardRevision, number int) (*models.CardRevision, error) {
+	for i := range revisions {
+		if revisions[i].Revision == number {
+			return &revisions[i], nil
+		}
+	}
+	return nil, fmt.Errorf("версия %d не найдена", number)
+}
+
+// toRevisionResponse преобразует CardRevision в CardRevisionResponse
+func toRevisionResponse(revision models.CardRevision) models.CardRevisionResponse {
+	return models.CardRevisionResponse{
+		Revision:    revision.Revision,
+		Image:       utils.ImageURL(revision.Image),
+		Title:       revision.Title,
+		Description: revision.Description,
+		Text:        revision.Text,
+		CreatedAt:   revision.CreatedAt,
+	}
+}
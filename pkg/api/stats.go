@@ -0,0 +1,115 @@
+package api
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/user/roma/pkg/db"
+	"github.com/user/roma/pkg/jobs"
+	"github.com/user/roma/pkg/models"
+	"github.com/user/roma/pkg/utils"
+)
+
+// Период статистики карточки в днях
+const (
+	defaultStatsDays = 30
+	maxStatsDays     = 365
+)
+
+// GetCardStats возвращает автору статистику просмотров и лайков карточки
+func GetCardStats(c *fiber.Ctx) error {
+	// Получаем пользователя из локального хранилища (установленного middleware)
+	user, ok := c.Locals("user").(*models.User)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "пользователь не найден",
+		})
+	}
+
+	// Получаем ID карточки из URL
+	cardID := c.Params("cardId")
+	if cardID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "ID карточки не указан",
+		})
+	}
+
+	// Получаем карточку из базы данных
+	card, err := db.GetCardByID(cardID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "карточка не найдена",
+		})
+	}
+
+	// Статистика доступна только автору
+	if card.UserID != user.ID {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "нет прав на просмотр статистики этой карточки",
+		})
+	}
+
+	stats, err := db.GetCardStats(card, statsDays(c), time.Now())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "ошибка получения статистики карточки",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(stats)
+}
+
+// statsDays получает период статистики в днях из параметра days запроса
+func statsDays(c *fiber.Ctx) int {
+	days, err := strconv.Atoi(c.Query("days", strconv.Itoa(defaultStatsDays)))
+	if err != nil || days < 1 {
+		return defaultStatsDays
+	}
+	if days > maxStatsDays {
+		return maxStatsDays
+	}
+	return days
+}
+
+// trackCardView учитывает просмотр карточки. Просмотры автора не учитываются.
+func trackCardView(c *fiber.Ctx, card *models.Card, currentUserID string) {
+	if currentUserID != "" && currentUserID == card.UserID {
+		return
+	}
+
+	jobs.TrackCardView(models.CardView{
+		CardID:    card.ID,
+		ViewerKey: viewerKey(c, currentUserID),
+		ViewedAt:  time.Now(),
+	})
+}
+
+// viewerKey определяет зрителя: авторизованный пользователь по ID, анонимный —
+// по HMAC IP-адреса с секретным ключом сервера, чтобы не хранить сами адреса
+func viewerKey(c *fiber.Ctx, currentUserID string) string {
+	if currentUserID != "" {
+		return "user:" + currentUserID
+	}
+	return "ip:" + utils.KeyedHash(c.IP())
+}
+
+// GetProfileStats возвращает текущему пользователю сводную статистику его карточек
+func GetProfileStats(c *fiber.Ctx) error {
+	// Получаем пользователя из локального хранилища (установленного middleware)
+	user, ok := c.Locals("user").(*models.User)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "пользователь не найден",
+		})
+	}
+
+	stats, err := db.GetProfileStats(user.ID, statsDays(c), time.Now())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "ошибка получения статистики профиля",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(stats)
+}
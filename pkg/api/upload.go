@@ -0,0 +1,379 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/user/roma/pkg/db"
+	"github.com/user/roma/pkg/models"
+	"github.com/user/roma/pkg/utils"
+)
+
+// Возобновляемые загрузки реализуют протокол tus 1.0.0 (https://tus.io/protocols/resumable-upload)
+// с расширениями creation, expiration и termination
+const (
+	tusVersion    = "1.0.0"
+	tusExtensions = "creation,expiration,termination"
+	// tusContentType — тип тела запроса с частью файла
+	tusContentType = "application/offset+octet-stream"
+)
+
+const (
+	// MaxActiveUploads — максимальное количество незавершенных и неиспользованных загрузок пользователя
+	MaxActiveUploads = 10
+	// maxUploadMetadata — максимальная длина заголовка Upload-Metadata
+	maxUploadMetadata = 1024
+)
+
+// UploadOptions сообщает клиенту tus параметры сервера
+func UploadOptions(c *fiber.Ctx) error {
+	c.Set("Tus-Resumable", tusVersion)
+	c.Set("Tus-Version", tusVersion)
+	c.Set("Tus-Extension", tusExtensions)
+	c.Set("Tus-Max-Size", strconv.Itoa(utils.MaxImageSize))
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// CreateUpload создает возобновляемую загрузку. Размер файла передается в заголовке
+// Upload-Length, части файла затем отправляются запросами PATCH на адрес из Location.
+func CreateUpload(c *fiber.Ctx) error {
+	// Получаем пользователя из локального хранилища (установленного middleware)
+	user, ok := c.Locals("user").(*models.User)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "пользователь не найден",
+		})
+	}
+
+	if err := checkTusVersion(c); err != nil {
+		return errorResponse(c, err)
+	}
+	c.Set("Tus-Resumable", tusVersion)
+
+	if c.Get("Upload-Defer-Length") != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "размер файла должен быть известен заранее (Upload-Length)",
+		})
+	}
+	length, err := strconv.ParseInt(c.Get("Upload-Length"), 10, 64)
+	if err != nil || length <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "неверный размер файла в заголовке Upload-Length",
+		})
+	}
+	if length > utils.MaxImageSize {
+		return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{
+			"error": "размер изображения превышает максимально допустимый",
+		})
+	}
+
+	metadata := c.Get("Upload-Metadata")
+	if len(metadata) > maxUploadMetadata {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "слишком длинный заголовок Upload-Metadata",
+		})
+	}
+
+	active, err := db.CountActiveUploads(user.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "ошибка проверки загрузок",
+		})
+	}
+	if active >= MaxActiveUploads {
+		return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+			"error": "слишком много незавершенных загрузок, завершите или удалите одну из них",
+		})
+	}
+
+	upload, err := db.CreateUpload(user.ID, length, metadata, time.Now().Add(utils.UploadExpiration))
+	if err != nil {
+		log.Printf("Ошибка создания загрузки: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "ошибка создания загрузки",
+		})
+	}
+
+	c.Location("/api/uploads/" + upload.ID)
+	c.Set("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+	return c.Status(fiber.StatusCreated).JSON(upload)
+}
+
+// HeadUpload сообщает, сколько байт загрузки уже получено (Upload-Offset),
+// чтобы клиент продолжил с этого места после обрыва связи
+func HeadUpload(c *fiber.Ctx) error {
+	c.Set("Tus-Resumable", tusVersion)
+	c.Set(fiber.HeaderCacheControl, "no-store")
+
+	upload, err := ownUpload(c)
+	if err != nil {
+		var fiberErr *fiber.Error
+		if errors.As(err, &fiberErr) {
+			return c.SendStatus(fiberErr.Code)
+		}
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+
+	setUploadHeaders(c, upload)
+	c.Set("Upload-Length", strconv.FormatInt(upload.Length, 10))
+	if upload.Metadata != "" {
+		c.Set("Upload-Metadata", upload.Metadata)
+	}
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// GetUpload возвращает состояние загрузки в JSON для клиентов без поддержки tus
+func GetUpload(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderCacheControl, "no-store")
+
+	upload, err := ownUpload(c)
+	if err != nil {
+		return errorResponse(c, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(upload)
+}
+
+// PatchUpload дописывает часть файла. Заголовок Upload-Offset должен совпадать
+// с количеством уже полученных байт. Часть записывается по мере получения, поэтому
+// после обрыва соединения клиент узнает через HEAD, сколько байт дошло, и продолжает с этого места.
+func PatchUpload(c *fiber.Ctx) (err error) {
+	// Тело отклоненного запроса остается непрочитанным в соединении, поэтому соединение закрываем
+	defer func() {
+		if c.Response().StatusCode() != fiber.StatusNoContent {
+			c.Context().SetConnectionClose()
+		}
+	}()
+
+	if err := checkTusVersion(c); err != nil {
+		return errorResponse(c, err)
+	}
+	c.Set("Tus-Resumable", tusVersion)
+
+	if c.Get(fiber.HeaderContentType) != tusContentType {
+		return c.Status(fiber.StatusUnsupportedMediaType).JSON(fiber.Map{
+			"error": "часть файла передается с типом " + tusContentType,
+		})
+	}
+	offset, err := strconv.ParseInt(c.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "неверное смещение в заголовке Upload-Offset",
+		})
+	}
+
+	upload, err := ownUpload(c)
+	if err != nil {
+		return errorResponse(c, err)
+	}
+
+	if offset != upload.Offset {
+		setUploadHeaders(c, upload)
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "смещение не совпадает с полученным размером файла",
+		})
+	}
+	remaining := upload.Length - offset
+	if length := c.Request().Header.ContentLength(); length > 0 && int64(length) > remaining {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "часть выходит за пределы размера файла",
+		})
+	}
+
+	// Читаем тело потоком (StreamRequestBody), не дожидаясь получения всей части
+	var body io.Reader = c.Context().RequestBodyStream()
+	if body == nil {
+		body = bytes.NewReader(c.Body())
+	}
+	conn := c.Context().Conn()
+	defer conn.SetReadDeadline(time.Time{})
+	stream := &idleTimeoutReader{reader: body, conn: conn}
+
+	upload.Offset, err = utils.AppendPartialUpload(upload.ID, offset, stream, remaining)
+	switch {
+	case errors.Is(err, utils.ErrUploadOffset):
+		// Другой запрос успел дописать часть файла
+		setUploadHeaders(c, upload)
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, utils.ErrUploadBusy):
+		upload.Offset = offset
+		setUploadHeaders(c, upload)
+		return c.Status(fiber.StatusLocked).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case stream.err != nil:
+		// Соединение оборвалось или замолчало: полученное начало части уже записано
+		log.Printf("Передача части загрузки %s прервана на %d байт: %v", upload.ID, upload.Offset, stream.err)
+		setUploadHeaders(c, upload)
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "передача части файла прервана",
+		})
+	case err != nil:
+		log.Printf("Ошибка записи части загрузки %s: %v", upload.ID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "ошибка записи части файла",
+		})
+	}
+
+	// Данные сверх размера файла (при передаче без Content-Length) не принимаются
+	if upload.Offset == upload.Length {
+		var extra [1]byte
+		if n, _ := stream.Read(extra[:]); n > 0 {
+			setUploadHeaders(c, upload)
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "часть выходит за пределы размера файла",
+			})
+		}
+	}
+
+	setUploadHeaders(c, upload)
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// partialUploadIdleTimeout — сколько ждать следующих байт части файла, прежде чем считать
+// соединение оборванным. Без него замолчавший клиент держал бы загрузку занятой.
+const partialUploadIdleTimeout = 30 * time.Second
+
+// idleTimeoutReader читает тело запроса, продлевая срок чтения из соединения перед каждым
+// чтением, и запоминает ошибку чтения, чтобы отличить обрыв соединения от ошибки записи
+type idleTimeoutReader struct {
+	reader io.Reader
+	conn   net.Conn
+	err    error
+}
+
+func (r *idleTimeoutReader) Read(p []byte) (int, error) {
+	r.conn.SetReadDeadline(time.Now().Add(partialUploadIdleTimeout))
+	n, err := r.reader.Read(p)
+	if err != nil && err != io.EOF {
+		r.err = err
+	}
+	return n, err
+}
+
+// DeleteUpload прерывает загрузку и удаляет полученные части
+func DeleteUpload(c *fiber.Ctx) error {
+	if err := checkTusVersion(c); err != nil {
+		return errorResponse(c, err)
+	}
+	c.Set("Tus-Resumable", tusVersion)
+
+	upload, err := ownUpload(c)
+	if err != nil {
+		return errorResponse(c, err)
+	}
+
+	if err := removeUpload(upload.ID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "ошибка удаления загрузки",
+		})
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// checkTusVersion отклоняет запросы клиентов tus другой версии протокола.
+// Запросы без заголовка Tus-Resumable принимаются.
+func checkTusVersion(c *fiber.Ctx) error {
+	if version := c.Get("Tus-Resumable"); version != "" && version != tusVersion {
+		c.Set("Tus-Version", tusVersion)
+		return fiber.NewError(fiber.StatusPreconditionFailed, "поддерживается версия протокола tus "+tusVersion)
+	}
+	return nil
+}
+
+// ownUpload возвращает загрузку из URL, если она принадлежит текущему пользователю и не истекла
+func ownUpload(c *fiber.Ctx) (*models.Upload, error) {
+	user, ok := c.Locals("user").(*models.User)
+	if !ok {
+		return nil, fiber.NewError(fiber.StatusUnauthorized, "пользователь не найден")
+	}
+	return findUpload(user.ID, c.Params("uploadId"))
+}
+
+// findUpload загружает загрузку пользователя и заполняет количество полученных байт
+func findUpload(userID, uploadID string) (*models.Upload, error) {
+	upload, err := db.GetUpload(uploadID)
+	if err == sql.ErrNoRows || (err == nil && upload.UserID != userID) {
+		return nil, fiber.NewError(fiber.StatusNotFound, "загрузка не найдена")
+	}
+	if err != nil {
+		log.Printf("Ошибка получения загрузки %s: %v", uploadID, err)
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "ошибка получения загрузки")
+	}
+	if !time.Now().Before(upload.ExpiresAt) {
+		return nil, fiber.NewError(fiber.StatusGone, "срок загрузки истек")
+	}
+
+	upload.Offset, err = utils.PartialUploadSize(upload.ID)
+	if err != nil {
+		log.Printf("Ошибка чтения загрузки %s: %v", uploadID, err)
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "ошибка получения загрузки")
+	}
+	upload.Complete = upload.Offset == upload.Length
+	return upload, nil
+}
+
+// setUploadHeaders задает заголовки tus с состоянием загрузки
+func setUploadHeaders(c *fiber.Ctx, upload *models.Upload) {
+	c.Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	c.Set("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+}
+
+// removeUpload удаляет загрузку вместе с полученными частями
+func removeUpload(uploadID string) error {
+	if err := utils.RemovePartialUpload(uploadID); err != nil {
+		log.Printf("Ошибка удаления частей загрузки %s: %v", uploadID, err)
+		return err
+	}
+	if err := db.DeleteUpload(uploadID); err != nil {
+		log.Printf("Ошибка удаления загрузки %s: %v", uploadID, err)
+		return err
+	}
+	return nil
+}
+
+// saveUploadedImage сохраняет изображение из завершенной загрузки пользователя.
+// Загрузка используется один раз: после успешного запроса ее удаляет finishUploads,
+// а при ошибке загрузку можно указать повторно.
+func saveUploadedImage(userID, uploadID string, kind utils.ImageKind) (string, error) {
+	upload, err := findUpload(userID, uploadID)
+	if err != nil {
+		var fiberErr *fiber.Error
+		if errors.As(err, &fiberErr) && fiberErr.Code != fiber.StatusInternalServerError {
+			return "", fiber.NewError(fiber.StatusBadRequest, fiberErr.Message)
+		}
+		return "", err
+	}
+	if !upload.Complete {
+		return "", fiber.NewError(fiber.StatusBadRequest, "загрузка еще не завершена")
+	}
+
+	src, err := utils.OpenPartialUpload(upload.ID)
+	if err != nil {
+		log.Printf("Ошибка открытия загрузки %s: %v", upload.ID, err)
+		return "", fiber.NewError(fiber.StatusInternalServerError, "ошибка при обработке файла")
+	}
+	filename, err := utils.SaveImage(src, kind)
+	src.Close()
+	return filename, imageUploadError(err)
+}
+
+// finishUploads удаляет загрузки, изображения из которых сохранены
+func finishUploads(uploadIDs ...string) {
+	for _, uploadID := range uploadIDs {
+		if uploadID != "" {
+			removeUpload(uploadID)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package api
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/user/roma/pkg/db"
+	"github.com/user/roma/pkg/models"
+)
+
+// GetUserMentions получает карточки, в которых упомянут пользователь, с пагинацией
+func GetUserMentions(c *fiber.Ctx) error {
+	// Получаем ID пользователя из URL
+	userID := c.Params("userId")
+	if userID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "ID пользователя не указан",
+		})
+	}
+
+	// Получаем параметры пагинации из запроса
+	page, err := strconv.Atoi(c.Query("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	limit, err := strconv.Atoi(c.Query("limit", "4"))
+	if err != nil || limit < 1 {
+		limit = 4
+	}
+
+	// Получаем текущего пользователя, если авторизован
+	var currentUserID string
+	if user, ok := c.Locals("user").(*models.User); ok {
+		currentUserID = user.ID
+	}
+
+	// Получаем карточки с упоминаниями, видимые текущему пользователю
+	response, err := db.GetMentionCards(userID, page, limit, currentUserID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "ошибка получения упоминаний пользователя",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(response)
+}
@@ -0,0 +1,215 @@
+// Package contentcheck проверяет пользовательский текст набором настраиваемых правил
+// (запрещенные слова, количество ссылок, повторные публикации) перед сохранением.
+package contentcheck
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Действия правил, упорядоченные по строгости
+const (
+	ActionAllow  = "allow"  // пропустить, совпадение только записывается в лог
+	ActionHold   = "hold"   // сохранить, но скрыть до проверки модератором
+	ActionReject = "reject" // отклонить запрос
+)
+
+// Виды проверяемого содержимого
+const (
+	KindCard    = "card"
+	KindProfile = "profile"
+	KindReport  = "report"
+)
+
+// CardTextFields — количество текстовых полей карточки (заголовок, описание и текст),
+// с которых начинается Input.Fields; за ними идут подписи и альтернативный текст изображений
+const CardTextFields = 3
+
+// DefaultRulesFile — файл правил по умолчанию
+const DefaultRulesFile = "content_rules.json"
+
+// Input представляет проверяемое содержимое
+type Input struct {
+	Kind   string   // вид содержимого (KindCard, KindProfile, KindReport)
+	UserID string   // автор
+	CardID string   // ID обновляемой карточки (пусто при создании)
+	Fields []string // проверяемые текстовые поля
+}
+
+// Violation описывает сработавшее правило
+type Violation struct {
+	Rule   string `json:"rule"`
+	Action string `json:"action"`
+	Reason string `json:"reason"`
+}
+
+// Result представляет итог проверки: самое строгое действие среди сработавших правил
+type Result struct {
+	Action     string      `json:"action"`
+	Violations []Violation `json:"violations"`
+}
+
+// Summary возвращает краткое описание сработавших правил для модератора
+func (r Result) Summary() string {
+	parts := make([]string, 0, len(r.Violations))
+	for _, v := range r.Violations {
+		parts = append(parts, fmt.Sprintf("%s: %s", v.Rule, v.Reason))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// Rule — правило проверки. Check возвращает причину срабатывания или пустую строку.
+type Rule interface {
+	Check(input Input) (string, error)
+}
+
+// RuleConfig описывает правило в файле правил. Набор используемых полей зависит от типа.
+type RuleConfig struct {
+	Name   string   `json:"name"`
+	Type   string   `json:"type"`
+	Action string   `json:"action"`
+	Words  []string `json:"words,omitempty"`  // blocked_words
+	Max    int      `json:"max,omitempty"`    // links
+	Window string   `json:"window,omitempty"` // duplicate
+}
+
+// RuleFactory создает правило по его описанию
+type RuleFactory func(config RuleConfig) (Rule, error)
+
+// factories хранит зарегистрированные типы правил
+var factories = map[string]RuleFactory{}
+
+// RegisterRule регистрирует тип правила, доступный в файле правил
+func RegisterRule(ruleType string, factory RuleFactory) {
+	factories[ruleType] = factory
+}
+
+// namedRule связывает правило с его именем и действием
+type namedRule struct {
+	name   string
+	action string
+	rule   Rule
+}
+
+// pipeline хранит текущий набор правил и сведения о файле, из которого он загружен
+var pipeline = struct {
+	sync.RWMutex
+	rules   []namedRule
+	path    string
+	modTime time.Time
+}{}
+
+// Load загружает правила из файла. При ошибке текущие правила не меняются.
+func Load(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var file struct {
+		Rules []RuleConfig `json:"rules"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("неверный формат файла правил: %w", err)
+	}
+
+	rules := make([]namedRule, 0, len(file.Rules))
+	for _, config := range file.Rules {
+		switch config.Action {
+		case ActionAllow, ActionHold, ActionReject:
+		default:
+			return fmt.Errorf("правило %q: неизвестное действие %q", config.Name, config.Action)
+		}
+
+		factory, ok := factories[config.Type]
+		if !ok {
+			return fmt.Errorf("правило %q: неизвестный тип %q", config.Name, config.Type)
+		}
+		rule, err := factory(config)
+		if err != nil {
+			return fmt.Errorf("правило %q: %w", config.Name, err)
+		}
+		rules = append(rules, namedRule{name: config.Name, action: config.Action, rule: rule})
+	}
+
+	pipeline.Lock()
+	pipeline.rules = rules
+	pipeline.path = path
+	pipeline.modTime = info.ModTime()
+	pipeline.Unlock()
+
+	log.Printf("Загружено правил проверки содержимого: %d", len(rules))
+	return nil
+}
+
+// ReloadIfChanged перезагружает правила, если файл изменился с момента последней загрузки.
+// Файл с ошибкой не перечитывается повторно, пока его снова не изменят.
+func ReloadIfChanged(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	pipeline.Lock()
+	changed := path != pipeline.path || !info.ModTime().Equal(pipeline.modTime)
+	pipeline.path, pipeline.modTime = path, info.ModTime()
+	pipeline.Unlock()
+
+	if !changed {
+		return nil
+	}
+	return Load(path)
+}
+
+// Check прогоняет содержимое через все правила. Ошибки отдельных правил записываются
+// в лог и не блокируют публикацию.
+func Check(input Input) Result {
+	pipeline.RLock()
+	rules := pipeline.rules
+	pipeline.RUnlock()
+
+	result := Result{Action: ActionAllow, Violations: []Violation{}}
+	for _, r := range rules {
+		reason, err := r.rule.Check(input)
+		if err != nil {
+			log.Printf("Ошибка правила проверки содержимого %s: %v", r.name, err)
+			continue
+		}
+		if reason == "" {
+			continue
+		}
+
+		if r.action == ActionAllow {
+			log.Printf("Правило %s сработало для пользователя %s: %s", r.name, input.UserID, reason)
+			continue
+		}
+
+		result.Violations = append(result.Violations, Violation{Rule: r.name, Action: r.action, Reason: reason})
+		if severity(r.action) > severity(result.Action) {
+			result.Action = r.action
+		}
+	}
+	return result
+}
+
+// severity возвращает строгость действия
+func severity(action string) int {
+	switch action {
+	case ActionReject:
+		return 2
+	case ActionHold:
+		return 1
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,45 @@
+package contentcheck
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// homoglyphs сводит похожие по начертанию кириллические и греческие буквы к латинским,
+// чтобы «спам», набранный вперемешку латиницей и кириллицей, совпадал со словом из списка
+var homoglyphs = map[rune]rune{
+	// Кириллица
+	'а': 'a', 'в': 'b', 'е': 'e', 'к': 'k', 'м': 'm', 'н': 'h', 'о': 'o', 'р': 'p',
+	'с': 'c', 'т': 't', 'у': 'y', 'х': 'x', 'і': 'i', 'ј': 'j', 'ѕ': 's', 'ԁ': 'd',
+	'ӏ': 'l', 'ԛ': 'q', 'ԝ': 'w',
+	// Греческий алфавит
+	'α': 'a', 'β': 'b', 'ε': 'e', 'ι': 'i', 'κ': 'k', 'ν': 'v', 'ο': 'o', 'ρ': 'p',
+	'τ': 't', 'υ': 'u', 'χ': 'x',
+}
+
+// Normalize приводит текст к виду для сравнения: совместимая декомпозиция Unicode (NFKD),
+// удаление диакритики и невидимых символов, нижний регистр и замена гомоглифов
+func Normalize(s string) string {
+	var b strings.Builder
+	for _, r := range norm.NFKD.String(s) {
+		// Диакритические знаки и невидимые символы форматирования (например, U+200B) пропускаются
+		if unicode.Is(unicode.Mn, r) || unicode.Is(unicode.Cf, r) {
+			continue
+		}
+		r = unicode.ToLower(r)
+		if folded, ok := homoglyphs[r]; ok {
+			r = folded
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// tokenize разбивает нормализованный текст на слова из букв и цифр
+func tokenize(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
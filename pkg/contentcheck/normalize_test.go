@@ -0,0 +1,93 @@
+package contentcheck
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"латиница в нижнем регистре", "CaSiNo", "casino"},
+		{"кириллические гомоглифы", "cаsinо", "casino"}, // а и о кириллические
+		{"греческие гомоглифы", "cαsinο", "casino"},     // α и ο греческие
+		{"диакритика", "cásíñö", "casino"},
+		{"невидимые символы", "ca\u200bsi\u00adno", "casino"}, // пробел нулевой ширины и мягкий перенос
+		{"совместимые формы", "ｃａｓｉｎｏ", "casino"},             // полноширинные буквы
+		{"лигатуры", "ﬁle", "file"},
+		{"буквы без двойников остаются", "Жук", "жyk"},
+		{"й и ё теряют надстрочные знаки", "йё", "иe"},
+		{"пунктуация и цифры сохраняются", "Win $100!", "win $100!"},
+		{"пустая строка", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Normalize(tt.in); got != tt.want {
+				t.Errorf("Normalize(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"быстрый заработок!!!", []string{"быстрый", "заработок"}},
+		{"casino,casino", []string{"casino", "casino"}},
+		{"c-a-s-i-n-o", []string{"c", "a", "s", "i", "n", "o"}},
+		{"win100 $", []string{"win100"}},
+		{"  ", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got := tokenize(tt.in)
+			if len(got) == 0 && len(tt.want) == 0 {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("tokenize(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBlockedWordsRule(t *testing.T) {
+	rule, err := newBlockedWordsRule(RuleConfig{Words: []string{"casino", "быстрый заработок"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		field string
+		want  bool
+	}{
+		{"слово целиком", "лучшее casino города", true},
+		{"слово с заменой букв", "лучшее cаsinо города", true},
+		{"слово с невидимым символом", "ca\u200bsino", true},
+		{"фраза", "Быстрый   заработок без вложений", true},
+		{"фраза с гомоглифами", "быстрый зaрaботок", true}, // а латинские
+		{"часть другого слова", "casinos", false},
+		{"слова фразы не подряд", "быстрый и честный заработок", false},
+		{"чистый текст", "обычная карточка", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reason, err := rule.Check(Input{Kind: KindCard, Fields: []string{"заголовок", tt.field}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := reason != ""; got != tt.want {
+				t.Errorf("Check(%q) = %q, want match %v", tt.field, reason, tt.want)
+			}
+		})
+	}
+}
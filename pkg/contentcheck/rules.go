@@ -0,0 +1,146 @@
+package contentcheck
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/user/roma/pkg/db"
+)
+
+func init() {
+	RegisterRule("blocked_words", newBlockedWordsRule)
+	RegisterRule("links", newLinksRule)
+	RegisterRule("duplicate", newDuplicateRule)
+}
+
+// blockedWordsRule срабатывает, если текст содержит запрещенное слово или фразу.
+// Слова сравниваются целиком после нормализации, поэтому замена букв на похожие
+// из другого алфавита или вставка невидимых символов не помогает обойти фильтр.
+type blockedWordsRule struct {
+	words   []string   // слова в исходном виде для описания срабатывания
+	phrases [][]string // нормализованные слова фраз
+}
+
+func newBlockedWordsRule(config RuleConfig) (Rule, error) {
+	rule := &blockedWordsRule{}
+	for _, word := range config.Words {
+		tokens := tokenize(Normalize(word))
+		if len(tokens) > 0 {
+			rule.words = append(rule.words, word)
+			rule.phrases = append(rule.phrases, tokens)
+		}
+	}
+	if len(rule.phrases) == 0 {
+		return nil, errors.New("список запрещенных слов пуст")
+	}
+	return rule, nil
+}
+
+func (r *blockedWordsRule) Check(input Input) (string, error) {
+	for _, field := range input.Fields {
+		tokens := tokenize(Normalize(field))
+		for i, phrase := range r.phrases {
+			if containsPhrase(tokens, phrase) {
+				return fmt.Sprintf("запрещенное слово %q", r.words[i]), nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// containsPhrase проверяет, входит ли последовательность слов phrase в tokens
+func containsPhrase(tokens, phrase []string) bool {
+	for i := 0; i+len(phrase) <= len(tokens); i++ {
+		match := true
+		for j, word := range phrase {
+			if tokens[i+j] != word {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+// linkPattern находит ссылки в тексте
+var linkPattern = regexp.MustCompile(`(?i)(https?://|www\.)\S+`)
+
+// linksRule срабатывает, если общее количество ссылок в полях превышает допустимое
+type linksRule struct {
+	max int
+}
+
+func newLinksRule(config RuleConfig) (Rule, error) {
+	if config.Max < 0 {
+		return nil, errors.New("максимальное количество ссылок не может быть отрицательным")
+	}
+	return &linksRule{max: config.Max}, nil
+}
+
+func (r *linksRule) Check(input Input) (string, error) {
+	count := 0
+	for _, field := range input.Fields {
+		count += len(linkPattern.FindAllString(field, -1))
+	}
+	if count > r.max {
+		return fmt.Sprintf("слишком много ссылок: %d (допустимо %d)", count, r.max), nil
+	}
+	return "", nil
+}
+
+// duplicateRule срабатывает, если пользователь недавно уже публиковал карточку
+// с тем же текстом. Применяется только к карточкам.
+type duplicateRule struct {
+	window time.Duration
+}
+
+func newDuplicateRule(config RuleConfig) (Rule, error) {
+	window, err := time.ParseDuration(config.Window)
+	if err != nil || window <= 0 {
+		return nil, fmt.Errorf("неверное окно повторной публикации %q", config.Window)
+	}
+	return &duplicateRule{window: window}, nil
+}
+
+func (r *duplicateRule) Check(input Input) (string, error) {
+	if input.Kind != KindCard || input.UserID == "" {
+		return "", nil
+	}
+
+	// Сравнивается только текст карточки: подписи изображений не хранятся вместе с ним
+	fingerprint := cardFingerprint(input.Fields[:min(len(input.Fields), CardTextFields)]...)
+	if fingerprint == "" {
+		return "", nil
+	}
+
+	cards, err := db.GetRecentUserCards(input.UserID, time.Now().Add(-r.window))
+	if err != nil {
+		return "", err
+	}
+
+	for _, card := range cards {
+		if card.ID == input.CardID {
+			continue
+		}
+		if cardFingerprint(card.Title, card.Description, card.Text) == fingerprint {
+			return fmt.Sprintf("повтор карточки %s", card.ID), nil
+		}
+	}
+	return "", nil
+}
+
+// cardFingerprint сводит текст карточки к нормализованным словам, чтобы повтор
+// не маскировался регистром, пунктуацией или заменой букв
+func cardFingerprint(fields ...string) string {
+	tokens := []string{}
+	for _, field := range fields {
+		tokens = append(tokens, tokenize(Normalize(field))...)
+	}
+	return strings.Join(tokens, " ")
+}
@@ -0,0 +1,206 @@
+package db
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/user/roma/pkg/models"
+)
+
+// insertCardImages добавляет изображения в галерею карточки начиная с указанной позиции
+func insertCardImages(ex execer, cardID string, images []models.CardImage, position int) error {
+	for i, image := range images {
+		_, err := ex.Exec(`
+			INSERT INTO card_images (id, card_id, filename, position, caption, alt, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			uuid.NewString(), cardID, image.Filename, position+i, image.Caption, image.Alt, time.Now())
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// normalizeCardImages перенумеровывает позиции галереи подряд с нуля
+// и делает первое изображение обложкой карточки
+func normalizeCardImages(ex execer, cardID string) error {
+	rows, err := ex.Query(`
+		SELECT id, filename FROM card_images
+		WHERE card_id = ? ORDER BY position, created_at`, cardID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	ids := []string{}
+	cover := ""
+	for rows.Next() {
+		var id, filename string
+		if err := rows.Scan(&id, &filename); err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			cover = filename
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+
+	for position, id := range ids {
+		if _, err := ex.Exec("UPDATE card_images SET position = ? WHERE id = ?", position, id); err != nil {
+			return err
+		}
+	}
+
+	_, err = ex.Exec("UPDATE cards SET image = ? WHERE id = ?", cover, cardID)
+	return err
+}
+
+// setCardCover делает файл обложкой карточки, добавляя его в галерею при необходимости.
+// Пустое имя файла очищает галерею.
+func setCardCover(ex execer, cardID, filename string) error {
+	if filename == "" {
+		_, err := ex.Exec("DELETE FROM card_images WHERE card_id = ?", cardID)
+		if err != nil {
+			return err
+		}
+		return normalizeCardImages(ex, cardID)
+	}
+
+	res, err := ex.Exec("UPDATE card_images SET position = -1 WHERE card_id = ? AND filename = ?",
+		cardID, filename)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		err := insertCardImages(ex, cardID, []models.CardImage{{Filename: filename}}, -1)
+		if err != nil {
+			return err
+		}
+	}
+	return normalizeCardImages(ex, cardID)
+}
+
+// GetCardImages получает галерею карточки в порядке отображения
+func GetCardImages(cardID string) ([]models.CardImage, error) {
+	rows, err := DB.Query(`
+		SELECT id, card_id, filename, position, caption, alt, created_at
+		FROM card_images WHERE card_id = ? ORDER BY position, created_at`, cardID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	images := []models.CardImage{}
+	for rows.Next() {
+		var image models.CardImage
+		err := rows.Scan(&image.ID, &image.CardID, &image.Filename, &image.Position,
+			&image.Caption, &image.Alt, &image.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		images = append(images, image)
+	}
+
+	return images, rows.Err()
+}
+
+// RemoveCardImage удаляет изображение из галереи карточки.
+// Файл остается на диске, так как на него может ссылаться история версий.
+func RemoveCardImage(cardID, imageID string) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec("DELETE FROM card_images WHERE card_id = ? AND id = ?", cardID, imageID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return errors.New("изображение не найдено")
+	}
+
+	if err := normalizeCardImages(tx, cardID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ReorderCardImages задает новый порядок галереи; список должен содержать все изображения карточки
+func ReorderCardImages(cardID string, imageIDs []string) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var count int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM card_images WHERE card_id = ?", cardID).Scan(&count); err != nil {
+		return err
+	}
+	if count != len(imageIDs) {
+		return errors.New("порядок должен содержать все изображения карточки")
+	}
+
+	seen := map[string]bool{}
+	for position, id := range imageIDs {
+		if seen[id] {
+			return errors.New("изображение указано несколько раз")
+		}
+		seen[id] = true
+
+		res, err := tx.Exec("UPDATE card_images SET position = ? WHERE card_id = ? AND id = ?",
+			position, cardID, id)
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err == nil && n == 0 {
+			return errors.New("изображение не найдено")
+		}
+	}
+
+	if err := normalizeCardImages(tx, cardID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetCardImageFiles получает все файлы изображений карточки: текущую галерею,
+// обложку и изображения из истории версий
+func GetCardImageFiles(cardID string) ([]string, error) {
+	return cardImageFiles(DB, cardID)
+}
+
+// cardImageFiles получает файлы карточки в транзакции или вне ее
+func cardImageFiles(ex execer, cardID string) ([]string, error) {
+	rows, err := ex.Query(`
+		SELECT image FROM cards WHERE id = ? AND image IS NOT NULL AND image != ''
+		UNION
+		SELECT filename FROM card_images WHERE card_id = ?
+		UNION
+		SELECT image FROM card_revisions WHERE card_id = ? AND image IS NOT NULL AND image != ''`,
+		cardID, cardID, cardID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	files := []string{}
+	for rows.Next() {
+		var file string
+		if err := rows.Scan(&file); err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+
+	return files, rows.Err()
+}
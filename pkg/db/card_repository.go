@@ -7,46 +7,176 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/user/roma/pkg/models"
+	"github.com/user/roma/pkg/utils"
 )
 
+// cardColumns перечисляет колонки карточки в порядке, ожидаемом scanCard
+const cardColumns = `id, user_id, user_name, image, title, description, text, likes, status, publish_at, visibility, deleted_at, reposts, quote_card_id, pin_position, hidden_at, created_at, updated_at`
+
+// rowScanner объединяет *sql.Row и *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanCard читает карточку из строки результата запроса
+func scanCard(row rowScanner) (*models.Card, error) {
+	card := &models.Card{}
+	var publishAt, deletedAt, hiddenAt sql.NullTime
+	var quoteCardID sql.NullString
+	var pinPosition sql.NullInt64
+	err := row.Scan(&card.ID, &card.UserID, &card.UserName, &card.Image, &card.Title,
+		&card.Description, &card.Text, &card.Likes, &card.Status, &publishAt,
+		&card.Visibility, &deletedAt, &card.Reposts, &quoteCardID, &pinPosition,
+		&hiddenAt, &card.CreatedAt, &card.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	card.QuoteCardID = quoteCardID.String
+	if pinPosition.Valid {
+		position := int(pinPosition.Int64)
+		card.PinPosition = &position
+	}
+	if publishAt.Valid {
+		card.PublishAt = &publishAt.Time
+	}
+	if deletedAt.Valid {
+		card.DeletedAt = &deletedAt.Time
+	}
+	if hiddenAt.Valid {
+		card.HiddenAt = &hiddenAt.Time
+	}
+	return card, nil
+}
+
+// ToCardResponse преобразует Card в CardResponse
+func ToCardResponse(card *models.Card, isLiked bool) models.CardResponse {
+	// Формируем галерею в порядке отображения
+	images := []models.CardImageResponse{}
+	for _, image := range card.Images {
+		images = append(images, models.CardImageResponse{
+			ID:       image.ID,
+			URL:      utils.ImageURL(image.Filename),
+			SrcSet:   utils.ImageSrcSet(image.Filename),
+			Position: image.Position,
+			Caption:  image.Caption,
+			Alt:      image.Alt,
+		})
+	}
+
+	return models.CardResponse{
+		ID:          card.ID,
+		UserID:      card.UserID,
+		UserName:    card.UserName,
+		Image:       utils.ImageURL(card.Image), // обложка сохраняется для старых клиентов
+		ImageSrcSet: utils.ImageSrcSet(card.Image),
+		Images:      images,
+		Title:       card.Title,
+		Description: card.Description,
+		Text:        card.Text,
+		TextHTML:    utils.RenderMarkdown(card.Text),
+		Entities:    toCardEntities(card),
+		Likes:       card.Likes,
+		IsLiked:     isLiked,
+		Reposts:     card.Reposts,
+		IsPinned:    card.PinPosition != nil,
+		Visibility:  card.Visibility,
+		Status:      card.Status,
+		PublishAt:   card.PublishAt,
+		DeletedAt:   card.DeletedAt,
+		HiddenAt:    card.HiddenAt,
+		CreatedAt:   card.CreatedAt,
+	}
+}
+
 // CreateCard создает новую карточку в базе данных
 func CreateCard(card models.CardCreate, userID, userName string) (*models.Card, error) {
+	// По умолчанию карточка публикуется сразу
+	status := card.Status
+	if status == "" {
+		status = models.CardStatusPublished
+	}
+
+	// По умолчанию карточка видна всем
+	visibility := card.Visibility
+	if visibility == "" {
+		visibility = models.CardVisibilityPublic
+	}
+
+	// Первое изображение галереи становится обложкой
+	cover := ""
+	if len(card.Images) > 0 {
+		cover = card.Images[0].Filename
+	}
+
 	// Создание новой карточки
 	newCard := &models.Card{
 		ID:          uuid.NewString(),
 		UserID:      userID,
 		UserName:    userName,
-		Image:       card.Image,
+		Image:       cover,
 		Title:       card.Title,
 		Description: card.Description,
 		Text:        card.Text,
 		Likes:       0,
 		LikedBy:     []string{},
+		Visibility:  visibility,
+		Status:      status,
+		PublishAt:   card.PublishAt,
+		QuoteCardID: card.QuoteCardID,
 		CreatedAt:   time.Now(),
 		UpdatedAt:   time.Now(),
 	}
 
-	// Добавление карточки в базу данных
-	_, err := DB.Exec(`
-		INSERT INTO cards (id, user_id, user_name, image, title, description, text, likes, created_at, updated_at) 
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+	// Добавление карточки и ее первой версии в базу данных
+	tx, err := DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		INSERT INTO cards (id, user_id, user_name, image, title, description, text, likes, status, publish_at, visibility, quote_card_id, created_at, updated_at) 
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
 		newCard.ID, newCard.UserID, newCard.UserName, newCard.Image, newCard.Title,
-		newCard.Description, newCard.Text, newCard.Likes, newCard.CreatedAt, newCard.UpdatedAt)
+		newCard.Description, newCard.Text, newCard.Likes, newCard.Status, newCard.PublishAt,
+		newCard.Visibility, sql.NullString{String: newCard.QuoteCardID, Valid: newCard.QuoteCardID != ""},
+		newCard.CreatedAt, newCard.UpdatedAt)
 	if err != nil {
 		return nil, err
 	}
 
-	return newCard, nil
+	if err := insertCardImages(tx, newCard.ID, card.Images, 0); err != nil {
+		return nil, err
+	}
+
+	if err := syncCardEntities(tx, newCard.ID); err != nil {
+		return nil, err
+	}
+
+	if err := createRevision(tx, newCard.ID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return GetCardByID(newCard.ID)
 }
 
-// GetCardByID получает карточку по ID
+// GetCardByID получает карточку по ID (удаленные в корзину карточки не возвращаются)
 func GetCardByID(id string) (*models.Card, error) {
-	card := &models.Card{}
-	err := DB.QueryRow(`
-		SELECT id, user_id, user_name, image, title, description, text, likes, created_at, updated_at 
-		FROM cards WHERE id = ?`, id).Scan(
-		&card.ID, &card.UserID, &card.UserName, &card.Image, &card.Title,
-		&card.Description, &card.Text, &card.Likes, &card.CreatedAt, &card.UpdatedAt)
+	return getCard(id, "deleted_at IS NULL")
+}
+
+// GetDeletedCardByID получает карточку из корзины по ID
+func GetDeletedCardByID(id string) (*models.Card, error) {
+	return getCard(id, "deleted_at IS NOT NULL")
+}
+
+// getCard получает карточку по ID с дополнительным условием
+func getCard(id, condition string) (*models.Card, error) {
+	card, err := scanCard(DB.QueryRow("SELECT "+cardColumns+" FROM cards WHERE id = ? AND "+condition, id))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, errors.New("карточка не найдена")
@@ -71,14 +201,43 @@ func GetCardByID(id string) (*models.Card, error) {
 	}
 	card.LikedBy = likedBy
 
+	// Получаем галерею карточки
+	card.Images, err = GetCardImages(id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Получаем упомянутых пользователей
+	card.MentionedUsers, err = getCardMentionedUsers(id)
+	if err != nil {
+		return nil, err
+	}
+
 	return card, nil
 }
 
 // GetAllCards получает все карточки с пагинацией
 func GetAllCards(page, limit int, currentUserID string) (*models.PaginationResponse, error) {
+	// В общей ленте показываются только опубликованные карточки, видимые пользователю
+	hidden, err := hiddenAuthorIDs()
+	if err != nil {
+		return nil, err
+	}
+	condition, args := listedCardsCondition(currentUserID, hidden)
+	return queryCardsPage(condition, args, "created_at DESC", page, limit, currentUserID)
+}
+
+// GetTrashCards получает удаленные карточки пользователя с пагинацией
+func GetTrashCards(userID string, page, limit int) (*models.PaginationResponse, error) {
+	return queryCardsPage("user_id = ? AND deleted_at IS NOT NULL", []any{userID},
+		"deleted_at DESC", page, limit, userID)
+}
+
+// queryCardsPage получает страницу карточек, удовлетворяющих условию
+func queryCardsPage(condition string, args []any, orderBy string, page, limit int, currentUserID string) (*models.PaginationResponse, error) {
 	// Получаем общее количество карточек
 	var totalCards int
-	err := DB.QueryRow("SELECT COUNT(*) FROM cards").Scan(&totalCards)
+	err := DB.QueryRow("SELECT COUNT(*) FROM cards WHERE "+condition, args...).Scan(&totalCards)
 	if err != nil {
 		return nil, err
 	}
@@ -96,8 +255,9 @@ func GetAllCards(page, limit int, currentUserID string) (*models.PaginationRespo
 
 	// Получаем карточки для текущей страницы
 	rows, err := DB.Query(`
-		SELECT id, user_id, user_name, image, title, description, text, likes, created_at, updated_at 
-		FROM cards ORDER BY created_at DESC LIMIT ? OFFSET ?`, limit, offset)
+		SELECT `+cardColumns+` 
+		FROM cards WHERE `+condition+` ORDER BY `+orderBy+` LIMIT ? OFFSET ?`,
+		append(args, limit, offset)...)
 	if err != nil {
 		return nil, err
 	}
@@ -105,50 +265,18 @@ func GetAllCards(page, limit int, currentUserID string) (*models.PaginationRespo
 
 	cardResponses := []models.CardResponse{}
 
-	// Базовый URL для изображений
-	baseURL := "http://localhost:3000/uploads/" // Порт берется из конфигурации сервера
-
 	// Формируем ответ
 	for rows.Next() {
-		var card models.Card
-
-		if err := rows.Scan(&card.ID, &card.UserID, &card.UserName, &card.Image, &card.Title,
-			&card.Description, &card.Text, &card.Likes, &card.CreatedAt, &card.UpdatedAt); err != nil {
+		card, err := scanCard(rows)
+		if err != nil {
 			return nil, err
 		}
 
-		// Проверяем, поставил ли текущий пользователь лайк
-		isLiked := false
-		if currentUserID != "" {
-			var exists bool
-			err := DB.QueryRow("SELECT EXISTS(SELECT 1 FROM likes WHERE user_id = ? AND card_id = ?)",
-				currentUserID, card.ID).Scan(&exists)
-			if err != nil {
-				return nil, err
-			}
-			isLiked = exists
-		}
-
-		// Формируем полный URL для изображения
-		imageURL := ""
-		if card.Image != "" {
-			imageURL = baseURL + card.Image
-		}
-
-		cardResponse := models.CardResponse{
-			ID:          card.ID,
-			UserID:      card.UserID,
-			UserName:    card.UserName,
-			Image:       imageURL,
-			Title:       card.Title,
-			Description: card.Description,
-			Text:        card.Text,
-			Likes:       card.Likes,
-			IsLiked:     isLiked,
-			CreatedAt:   card.CreatedAt,
+		response, err := listCardResponse(card, currentUserID)
+		if err != nil {
+			return nil, err
 		}
-
-		cardResponses = append(cardResponses, cardResponse)
+		cardResponses = append(cardResponses, response)
 	}
 
 	return &models.PaginationResponse{
@@ -159,111 +287,275 @@ func GetAllCards(page, limit int, currentUserID string) (*models.PaginationRespo
 	}, nil
 }
 
-// GetUserCards получает карточки пользователя с пагинацией
-func GetUserCards(userID string, page, limit int, currentUserID string) (*models.PaginationResponse, error) {
-	// Получаем общее количество карточек пользователя
-	var totalCards int
-	err := DB.QueryRow("SELECT COUNT(*) FROM cards WHERE user_id = ?", userID).Scan(&totalCards)
+// listCardResponse дополняет карточку из списка галереей, упоминаниями и цитатой
+// и формирует ответ для текущего пользователя
+func listCardResponse(card *models.Card, currentUserID string) (models.CardResponse, error) {
+	var err error
+
+	// Получаем галерею карточки
+	card.Images, err = GetCardImages(card.ID)
 	if err != nil {
-		return nil, err
+		return models.CardResponse{}, err
 	}
 
-	// Рассчитываем общее количество страниц
-	totalPages := (totalCards + limit - 1) / limit
+	// Получаем упомянутых пользователей
+	card.MentionedUsers, err = getCardMentionedUsers(card.ID)
+	if err != nil {
+		return models.CardResponse{}, err
+	}
 
-	// Если запрошенная страница больше общего количества страниц, возвращаем последнюю страницу
-	if page > totalPages && totalPages > 0 {
-		page = totalPages
+	// Проверяем, поставил ли текущий пользователь лайк
+	isLiked := false
+	if currentUserID != "" {
+		err := DB.QueryRow("SELECT EXISTS(SELECT 1 FROM likes WHERE user_id = ? AND card_id = ?)",
+			currentUserID, card.ID).Scan(&isLiked)
+		if err != nil {
+			return models.CardResponse{}, err
+		}
 	}
 
-	// Вычисляем смещение
-	offset := (page - 1) * limit
+	response := ToCardResponse(card, isLiked)
 
-	// Получаем карточки пользователя для текущей страницы
-	rows, err := DB.Query(`
-		SELECT id, user_id, user_name, image, title, description, text, likes, created_at, updated_at 
-		FROM cards WHERE user_id = ? ORDER BY created_at DESC LIMIT ? OFFSET ?`, userID, limit, offset)
+	// Цитируемая карточка показывается с учетом ее видимости для текущего пользователя
+	response.Quote, err = ToQuotedCard(card.QuoteCardID, currentUserID)
 	if err != nil {
-		return nil, err
+		return models.CardResponse{}, err
 	}
-	defer rows.Close()
 
-	cardResponses := []models.CardResponse{}
+	return response, nil
+}
 
-	// Базовый URL для изображений
-	baseURL := "http://localhost:3000/uploads/" // Порт берется из конфигурации сервера
+// UpdateCard обновляет карточку и сохраняет новую версию
+func UpdateCard(id string, card models.CardUpdate) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
 
-	// Формируем ответ
-	for rows.Next() {
-		var card models.Card
+	// Сохраняем исходное состояние карточки, если истории версий еще нет
+	if err := ensureBaselineRevision(tx, id); err != nil {
+		return err
+	}
 
-		if err := rows.Scan(&card.ID, &card.UserID, &card.UserName, &card.Image, &card.Title,
-			&card.Description, &card.Text, &card.Likes, &card.CreatedAt, &card.UpdatedAt); err != nil {
-			return nil, err
-		}
+	_, err = tx.Exec(`
+		UPDATE cards 
+		SET title = ?, description = ?, text = ?, status = ?, publish_at = ?, visibility = ?, updated_at = ? 
+		WHERE id = ?`,
+		card.Title, card.Description, card.Text, card.Status, card.PublishAt,
+		card.Visibility, time.Now(), id)
+	if err != nil {
+		return err
+	}
 
-		// Проверяем, поставил ли текущий пользователь лайк
-		isLiked := false
-		if currentUserID != "" {
-			var exists bool
-			err := DB.QueryRow("SELECT EXISTS(SELECT 1 FROM likes WHERE user_id = ? AND card_id = ?)",
-				currentUserID, card.ID).Scan(&exists)
-			if err != nil {
-				return nil, err
-			}
-			isLiked = exists
+	// Обновляем галерею; файлы убранных изображений остаются для истории версий
+	if card.RemoveImages {
+		if _, err := tx.Exec("DELETE FROM card_images WHERE card_id = ?", id); err != nil {
+			return err
 		}
-
-		// Формируем полный URL для изображения
-		imageURL := ""
-		if card.Image != "" {
-			imageURL = baseURL + card.Image
+	}
+	if card.CoverImage != nil {
+		_, err := tx.Exec(`
+			DELETE FROM card_images WHERE id = (
+				SELECT id FROM card_images WHERE card_id = ? ORDER BY position, created_at LIMIT 1)`, id)
+		if err != nil {
+			return err
 		}
-
-		cardResponse := models.CardResponse{
-			ID:          card.ID,
-			UserID:      card.UserID,
-			UserName:    card.UserName,
-			Image:       imageURL,
-			Title:       card.Title,
-			Description: card.Description,
-			Text:        card.Text,
-			Likes:       card.Likes,
-			IsLiked:     isLiked,
-			CreatedAt:   card.CreatedAt,
+		if err := insertCardImages(tx, id, []models.CardImage{*card.CoverImage}, -1); err != nil {
+			return err
+		}
+	}
+	if len(card.NewImages) > 0 {
+		var next int
+		err := tx.QueryRow("SELECT COALESCE(MAX(position), -1) + 1 FROM card_images WHERE card_id = ?",
+			id).Scan(&next)
+		if err != nil {
+			return err
+		}
+		if err := insertCardImages(tx, id, card.NewImages, next); err != nil {
+			return err
 		}
+	}
+	if err := normalizeCardImages(tx, id); err != nil {
+		return err
+	}
 
-		cardResponses = append(cardResponses, cardResponse)
+	if err := syncCardEntities(tx, id); err != nil {
+		return err
 	}
 
-	return &models.PaginationResponse{
-		Cards:      cardResponses,
-		Page:       page,
-		TotalPages: totalPages,
-		TotalCards: totalCards,
-	}, nil
+	if err := createRevision(tx, id); err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
-// UpdateCard обновляет карточку
-func UpdateCard(id string, card models.CardUpdate) error {
-	_, err := DB.Exec(`
-		UPDATE cards 
-		SET image = ?, title = ?, description = ?, text = ?, updated_at = ? 
-		WHERE id = ?`,
-		card.Image, card.Title, card.Description, card.Text, time.Now(), id)
+// SetCardStatus меняет статус публикации карточки
+func SetCardStatus(id, status string, publishAt *time.Time) error {
+	_, err := DB.Exec("UPDATE cards SET status = ?, publish_at = ?, updated_at = ? WHERE id = ?",
+		status, publishAt, time.Now(), id)
+	return err
+}
+
+// PublishDueCards публикует запланированные карточки, время публикации которых наступило,
+// и возвращает количество опубликованных карточек
+func PublishDueCards(now time.Time) (int, error) {
+	rows, err := DB.Query("SELECT id, publish_at FROM cards WHERE status = ? AND deleted_at IS NULL",
+		models.CardStatusScheduled)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	// Сравниваем время в Go, так как SQLite хранит его строкой
+	dueIDs := []string{}
+	for rows.Next() {
+		var id string
+		var publishAt sql.NullTime
+		if err := rows.Scan(&id, &publishAt); err != nil {
+			return 0, err
+		}
+		if !publishAt.Valid || !publishAt.Time.After(now) {
+			dueIDs = append(dueIDs, id)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	rows.Close()
+
+	published := 0
+	for _, id := range dueIDs {
+		res, err := DB.Exec("UPDATE cards SET status = ?, updated_at = ? WHERE id = ? AND status = ?",
+			models.CardStatusPublished, now, id, models.CardStatusScheduled)
+		if err != nil {
+			return published, err
+		}
+		if n, err := res.RowsAffected(); err == nil {
+			published += int(n)
+		}
+	}
+
+	return published, nil
+}
+
+// SoftDeleteCard перемещает карточку в корзину
+func SoftDeleteCard(id string) error {
+	// Удаленная карточка перестает быть закрепленной
+	_, err := DB.Exec("UPDATE cards SET deleted_at = ?, pin_position = NULL WHERE id = ? AND deleted_at IS NULL", time.Now(), id)
+	return err
+}
+
+// hideCard скрывает карточку по решению модератора: она остается видна только автору
+func hideCard(ex execer, id string) error {
+	_, err := ex.Exec("UPDATE cards SET hidden_at = ?, pin_position = NULL WHERE id = ? AND hidden_at IS NULL", time.Now(), id)
 	return err
 }
 
-// DeleteCard удаляет карточку
+// RestoreCard возвращает карточку из корзины
+func RestoreCard(id string) error {
+	_, err := DB.Exec("UPDATE cards SET deleted_at = NULL, updated_at = ? WHERE id = ?", time.Now(), id)
+	return err
+}
+
+// GetExpiredDeletedCards получает карточки, удаленные в корзину раньше указанного времени
+func GetExpiredDeletedCards(before time.Time) ([]models.Card, error) {
+	rows, err := DB.Query("SELECT " + cardColumns + " FROM cards WHERE deleted_at IS NOT NULL")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	// Сравниваем время в Go, так как SQLite хранит его строкой
+	cards := []models.Card{}
+	for rows.Next() {
+		card, err := scanCard(rows)
+		if err != nil {
+			return nil, err
+		}
+		if card.DeletedAt.Before(before) {
+			cards = append(cards, *card)
+		}
+	}
+
+	return cards, rows.Err()
+}
+
+// GetRecentUserCards получает неудаленные карточки пользователя, созданные после указанного времени
+func GetRecentUserCards(userID string, since time.Time) ([]models.Card, error) {
+	rows, err := DB.Query("SELECT "+cardColumns+" FROM cards WHERE user_id = ? AND deleted_at IS NULL", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	// Сравниваем время в Go, так как SQLite хранит его строкой
+	cards := []models.Card{}
+	for rows.Next() {
+		card, err := scanCard(rows)
+		if err != nil {
+			return nil, err
+		}
+		if card.CreatedAt.After(since) {
+			cards = append(cards, *card)
+		}
+	}
+
+	return cards, rows.Err()
+}
+
+// DeleteCard окончательно удаляет карточку вместе со всеми связанными записями
 func DeleteCard(id string) error {
+	return deleteCard(DB, id)
+}
+
+// deleteCard удаляет карточку и связанные с ней записи в транзакции или вне ее
+func deleteCard(ex execer, id string) error {
 	// Удаляем все лайки карточки
-	_, err := DB.Exec("DELETE FROM likes WHERE card_id = ?", id)
+	_, err := ex.Exec("DELETE FROM likes WHERE card_id = ?", id)
+	if err != nil {
+		return err
+	}
+
+	// Удаляем историю версий карточки
+	_, err = ex.Exec("DELETE FROM card_revisions WHERE card_id = ?", id)
+	if err != nil {
+		return err
+	}
+
+	// Удаляем галерею карточки
+	_, err = ex.Exec("DELETE FROM card_images WHERE card_id = ?", id)
+	if err != nil {
+		return err
+	}
+
+	// Удаляем репосты карточки
+	_, err = ex.Exec("DELETE FROM reposts WHERE card_id = ?", id)
+	if err != nil {
+		return err
+	}
+
+	// Удаляем статистику просмотров карточки
+	for _, table := range []string{"card_viewers", "card_daily_viewers", "card_view_stats"} {
+		_, err = ex.Exec("DELETE FROM "+table+" WHERE card_id = ?", id)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Удаляем упоминания и хештеги карточки
+	_, err = ex.Exec("DELETE FROM card_mentions WHERE card_id = ?", id)
+	if err != nil {
+		return err
+	}
+	_, err = ex.Exec("DELETE FROM card_hashtags WHERE card_id = ?", id)
 	if err != nil {
 		return err
 	}
 
 	// Удаляем карточку
-	_, err = DB.Exec("DELETE FROM cards WHERE id = ?", id)
+	_, err = ex.Exec("DELETE FROM cards WHERE id = ?", id)
 	return err
 }
 
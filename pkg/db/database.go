@@ -27,6 +27,9 @@ func InitDatabase() {
 	// Создаем таблицы, если они не существуют
 	createTables()
 
+	// Добавляем новые колонки в уже существующие таблицы
+	migrateTables()
+
 	log.Println("База данных успешно инициализирована")
 }
 
@@ -42,6 +45,11 @@ func createTables() {
 		profile_image TEXT,
 		profile_banner TEXT,
 		description TEXT,
+		role TEXT NOT NULL DEFAULT 'user',
+		suspended_at TIMESTAMP,
+		suspended_until TIMESTAMP,
+		suspend_reason TEXT,
+		shadow_banned_at TIMESTAMP,
 		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 	);`
@@ -57,6 +65,14 @@ func createTables() {
 		description TEXT,
 		text TEXT,
 		likes INTEGER DEFAULT 0,
+		status TEXT NOT NULL DEFAULT 'published',
+		publish_at TIMESTAMP,
+		visibility TEXT NOT NULL DEFAULT 'public',
+		deleted_at TIMESTAMP,
+		reposts INTEGER NOT NULL DEFAULT 0,
+		quote_card_id TEXT,
+		pin_position INTEGER,
+		hidden_at TIMESTAMP,
 		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
@@ -73,6 +89,156 @@ func createTables() {
 		FOREIGN KEY (card_id) REFERENCES cards(id) ON DELETE CASCADE
 	);`
 
+	// Создание таблицы версий карточек
+	createCardRevisionsTable := `
+	CREATE TABLE IF NOT EXISTS card_revisions (
+		id TEXT PRIMARY KEY,
+		card_id TEXT NOT NULL,
+		revision INTEGER NOT NULL,
+		image TEXT,
+		title TEXT NOT NULL,
+		description TEXT,
+		text TEXT,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE (card_id, revision),
+		FOREIGN KEY (card_id) REFERENCES cards(id) ON DELETE CASCADE
+	);`
+
+	// Создание таблицы подписок
+	createFollowsTable := `
+	CREATE TABLE IF NOT EXISTS follows (
+		follower_id TEXT NOT NULL,
+		followee_id TEXT NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (follower_id, followee_id),
+		FOREIGN KEY (follower_id) REFERENCES users(id) ON DELETE CASCADE,
+		FOREIGN KEY (followee_id) REFERENCES users(id) ON DELETE CASCADE
+	);`
+
+	// Создание таблицы изображений галереи карточек
+	createCardImagesTable := `
+	CREATE TABLE IF NOT EXISTS card_images (
+		id TEXT PRIMARY KEY,
+		card_id TEXT NOT NULL,
+		filename TEXT NOT NULL,
+		position INTEGER NOT NULL DEFAULT 0,
+		caption TEXT NOT NULL DEFAULT '',
+		alt TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (card_id) REFERENCES cards(id) ON DELETE CASCADE
+	);`
+
+	// Создание таблиц упоминаний и хештегов карточек
+	createCardMentionsTable := `
+	CREATE TABLE IF NOT EXISTS card_mentions (
+		card_id TEXT NOT NULL,
+		user_id TEXT NOT NULL,
+		PRIMARY KEY (card_id, user_id),
+		FOREIGN KEY (card_id) REFERENCES cards(id) ON DELETE CASCADE,
+		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+	);`
+
+	createCardHashtagsTable := `
+	CREATE TABLE IF NOT EXISTS card_hashtags (
+		card_id TEXT NOT NULL,
+		tag TEXT NOT NULL,
+		PRIMARY KEY (card_id, tag),
+		FOREIGN KEY (card_id) REFERENCES cards(id) ON DELETE CASCADE
+	);`
+
+	// Создание таблицы репостов
+	createRepostsTable := `
+	CREATE TABLE IF NOT EXISTS reposts (
+		user_id TEXT NOT NULL,
+		card_id TEXT NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (user_id, card_id),
+		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+		FOREIGN KEY (card_id) REFERENCES cards(id) ON DELETE CASCADE
+	);`
+
+	// Создание таблиц статистики просмотров карточек
+	createCardViewersTable := `
+	CREATE TABLE IF NOT EXISTS card_viewers (
+		card_id TEXT NOT NULL,
+		viewer_key TEXT NOT NULL,
+		last_viewed_at TIMESTAMP NOT NULL,
+		PRIMARY KEY (card_id, viewer_key),
+		FOREIGN KEY (card_id) REFERENCES cards(id) ON DELETE CASCADE
+	);`
+
+	createCardDailyViewersTable := `
+	CREATE TABLE IF NOT EXISTS card_daily_viewers (
+		card_id TEXT NOT NULL,
+		day TEXT NOT NULL,
+		viewer_key TEXT NOT NULL,
+		PRIMARY KEY (card_id, day, viewer_key),
+		FOREIGN KEY (card_id) REFERENCES cards(id) ON DELETE CASCADE
+	);`
+
+	createCardViewStatsTable := `
+	CREATE TABLE IF NOT EXISTS card_view_stats (
+		card_id TEXT NOT NULL,
+		day TEXT NOT NULL,
+		views INTEGER NOT NULL DEFAULT 0,
+		unique_viewers INTEGER NOT NULL DEFAULT 0,
+		PRIMARY KEY (card_id, day),
+		FOREIGN KEY (card_id) REFERENCES cards(id) ON DELETE CASCADE
+	);`
+
+	// Создание таблиц жалоб и журнала модерации
+	createReportsTable := `
+	CREATE TABLE IF NOT EXISTS reports (
+		id TEXT PRIMARY KEY,
+		card_id TEXT NOT NULL,
+		reporter_id TEXT NOT NULL,
+		reason TEXT NOT NULL,
+		comment TEXT NOT NULL DEFAULT '',
+		status TEXT NOT NULL DEFAULT 'open',
+		claimed_by TEXT,
+		claimed_at TIMESTAMP,
+		action TEXT,
+		resolution_note TEXT,
+		resolved_by TEXT,
+		resolved_at TIMESTAMP,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (reporter_id) REFERENCES users(id) ON DELETE CASCADE
+	);`
+
+	createModerationActionsTable := `
+	CREATE TABLE IF NOT EXISTS moderation_actions (
+		id TEXT PRIMARY KEY,
+		moderator_id TEXT NOT NULL,
+		report_id TEXT,
+		action TEXT NOT NULL,
+		target_type TEXT NOT NULL,
+		target_id TEXT NOT NULL,
+		note TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);`
+
+	// Файлы изображений хранятся по хэшу содержимого; refs — количество ссылок на файл
+	createImagesTable := `
+	CREATE TABLE IF NOT EXISTS images (
+		filename TEXT PRIMARY KEY,
+		hash TEXT NOT NULL,
+		size INTEGER NOT NULL,
+		refs INTEGER NOT NULL DEFAULT 0,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);`
+
+	// Возобновляемые загрузки; полученные части файла хранятся на диске
+	createUploadsTable := `
+	CREATE TABLE IF NOT EXISTS uploads (
+		id TEXT PRIMARY KEY,
+		user_id TEXT NOT NULL,
+		length INTEGER NOT NULL,
+		metadata TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		expires_at TIMESTAMP NOT NULL,
+		FOREIGN KEY (user_id) REFERENCES users(id)
+	);`
+
 	// Выполнение запросов создания таблиц
 	_, err := DB.Exec(createUsersTable)
 	if err != nil {
@@ -88,4 +254,126 @@ func createTables() {
 	if err != nil {
 		log.Fatalf("Ошибка создания таблицы лайков: %v", err)
 	}
+
+	_, err = DB.Exec(createCardRevisionsTable)
+	if err != nil {
+		log.Fatalf("Ошибка создания таблицы версий карточек: %v", err)
+	}
+
+	_, err = DB.Exec(createFollowsTable)
+	if err != nil {
+		log.Fatalf("Ошибка создания таблицы подписок: %v", err)
+	}
+
+	_, err = DB.Exec(createCardImagesTable)
+	if err != nil {
+		log.Fatalf("Ошибка создания таблицы изображений карточек: %v", err)
+	}
+
+	_, err = DB.Exec(createCardMentionsTable)
+	if err != nil {
+		log.Fatalf("Ошибка создания таблицы упоминаний: %v", err)
+	}
+
+	_, err = DB.Exec(createCardHashtagsTable)
+	if err != nil {
+		log.Fatalf("Ошибка создания таблицы хештегов: %v", err)
+	}
+
+	_, err = DB.Exec(createRepostsTable)
+	if err != nil {
+		log.Fatalf("Ошибка создания таблицы репостов: %v", err)
+	}
+
+	_, err = DB.Exec(createCardViewersTable)
+	if err != nil {
+		log.Fatalf("Ошибка создания таблицы зрителей карточек: %v", err)
+	}
+
+	_, err = DB.Exec(createCardDailyViewersTable)
+	if err != nil {
+		log.Fatalf("Ошибка создания таблицы дневных зрителей карточек: %v", err)
+	}
+
+	_, err = DB.Exec(createCardViewStatsTable)
+	if err != nil {
+		log.Fatalf("Ошибка создания таблицы статистики просмотров: %v", err)
+	}
+
+	_, err = DB.Exec(createReportsTable)
+	if err != nil {
+		log.Fatalf("Ошибка создания таблицы жалоб: %v", err)
+	}
+
+	_, err = DB.Exec(createModerationActionsTable)
+	if err != nil {
+		log.Fatalf("Ошибка создания журнала модерации: %v", err)
+	}
+
+	_, err = DB.Exec(createImagesTable)
+	if err != nil {
+		log.Fatalf("Ошибка создания таблицы файлов изображений: %v", err)
+	}
+
+	_, err = DB.Exec(createUploadsTable)
+	if err != nil {
+		log.Fatalf("Ошибка создания таблицы загрузок: %v", err)
+	}
+}
+
+// migrateTables добавляет колонки, появившиеся после создания таблиц
+func migrateTables() {
+	addColumnIfNotExists("cards", "status", "TEXT NOT NULL DEFAULT 'published'")
+	addColumnIfNotExists("cards", "publish_at", "TIMESTAMP")
+	addColumnIfNotExists("cards", "visibility", "TEXT NOT NULL DEFAULT 'public'")
+	addColumnIfNotExists("cards", "deleted_at", "TIMESTAMP")
+	addColumnIfNotExists("cards", "reposts", "INTEGER NOT NULL DEFAULT 0")
+	addColumnIfNotExists("cards", "quote_card_id", "TEXT")
+	addColumnIfNotExists("cards", "pin_position", "INTEGER")
+	addColumnIfNotExists("cards", "hidden_at", "TIMESTAMP")
+	addColumnIfNotExists("users", "role", "TEXT NOT NULL DEFAULT 'user'")
+	addColumnIfNotExists("users", "suspended_at", "TIMESTAMP")
+	addColumnIfNotExists("users", "suspend_reason", "TEXT")
+	addColumnIfNotExists("users", "suspended_until", "TIMESTAMP")
+	addColumnIfNotExists("users", "shadow_banned_at", "TIMESTAMP")
+	addColumnIfNotExists("images", "referenced_at", "TIMESTAMP")
+	addColumnIfNotExists("reports", "content_check", "TEXT NOT NULL DEFAULT ''")
+
+	// Переносим единственное изображение старых карточек в галерею
+	_, err := DB.Exec(`
+		INSERT INTO card_images (id, card_id, filename, position, created_at)
+		SELECT lower(hex(randomblob(16))), id, image, 0, created_at FROM cards
+		WHERE image IS NOT NULL AND image != ''
+			AND NOT EXISTS (SELECT 1 FROM card_images WHERE card_images.card_id = cards.id)`)
+	if err != nil {
+		log.Fatalf("Ошибка переноса изображений карточек в галерею: %v", err)
+	}
+
+	// Заполняем упоминания и хештеги старых карточек
+	backfillCardEntities()
+}
+
+// addColumnIfNotExists добавляет колонку в таблицу, если ее еще нет
+func addColumnIfNotExists(table, column, definition string) {
+	rows, err := DB.Query("SELECT name FROM pragma_table_info(?)", table)
+	if err != nil {
+		log.Fatalf("Ошибка чтения структуры таблицы %s: %v", table, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			log.Fatalf("Ошибка чтения структуры таблицы %s: %v", table, err)
+		}
+		if name == column {
+			return
+		}
+	}
+	rows.Close()
+
+	_, err = DB.Exec("ALTER TABLE " + table + " ADD COLUMN " + column + " " + definition)
+	if err != nil {
+		log.Fatalf("Ошибка добавления колонки %s в таблицу %s: %v", column, table, err)
+	}
 }
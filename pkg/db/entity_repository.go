@@ -0,0 +1,138 @@
+package db
+
+import (
+	"log"
+	"strings"
+
+	"github.com/user/roma/pkg/models"
+	"github.com/user/roma/pkg/utils"
+)
+
+// syncCardEntities пересобирает упоминания и хештеги карточки по ее текущему тексту
+func syncCardEntities(ex execer, cardID string) error {
+	var title, description, text string
+	err := ex.QueryRow("SELECT title, description, text FROM cards WHERE id = ?", cardID).Scan(
+		&title, &description, &text)
+	if err != nil {
+		return err
+	}
+
+	if _, err := ex.Exec("DELETE FROM card_mentions WHERE card_id = ?", cardID); err != nil {
+		return err
+	}
+	if _, err := ex.Exec("DELETE FROM card_hashtags WHERE card_id = ?", cardID); err != nil {
+		return err
+	}
+
+	for _, field := range []string{title, description, text} {
+		for _, entity := range utils.ParseEntities(field) {
+			switch entity.Type {
+			case models.EntityMention:
+				// Упоминания неизвестных логинов не сохраняются
+				_, err = ex.Exec(`
+					INSERT OR IGNORE INTO card_mentions (card_id, user_id)
+					SELECT ?, id FROM users WHERE lower(login) = lower(?)`, cardID, entity.Value)
+			case models.EntityHashtag:
+				_, err = ex.Exec("INSERT OR IGNORE INTO card_hashtags (card_id, tag) VALUES (?, ?)",
+					cardID, strings.ToLower(entity.Value))
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// getCardMentionedUsers получает упомянутых в карточке пользователей (логин в нижнем регистре -> ID)
+func getCardMentionedUsers(cardID string) (map[string]string, error) {
+	rows, err := DB.Query(`
+		SELECT users.id, users.login FROM card_mentions
+		JOIN users ON users.id = card_mentions.user_id
+		WHERE card_mentions.card_id = ?`, cardID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := map[string]string{}
+	for rows.Next() {
+		var id, login string
+		if err := rows.Scan(&id, &login); err != nil {
+			return nil, err
+		}
+		users[strings.ToLower(login)] = id
+	}
+
+	return users, rows.Err()
+}
+
+// GetMentionCards получает видимые пользователю карточки, в которых упомянут пользователь
+func GetMentionCards(userID string, page, limit int, currentUserID string) (*models.PaginationResponse, error) {
+	hidden, err := hiddenAuthorIDs()
+	if err != nil {
+		return nil, err
+	}
+	listed, args := listedCardsCondition(currentUserID, hidden)
+	condition := "id IN (SELECT card_id FROM card_mentions WHERE user_id = ?) AND " + listed
+	return queryCardsPage(condition, append([]any{userID}, args...), "created_at DESC", page, limit, currentUserID)
+}
+
+// toCardEntities находит сущности в полях карточки; упоминания возвращаются
+// только для существующих пользователей
+func toCardEntities(card *models.Card) models.CardEntities {
+	parse := func(text string) []models.TextEntity {
+		entities := []models.TextEntity{}
+		for _, entity := range utils.ParseEntities(text) {
+			if entity.Type == models.EntityMention {
+				userID, ok := card.MentionedUsers[strings.ToLower(entity.Value)]
+				if !ok {
+					continue
+				}
+				entity.UserID = userID
+			}
+			entities = append(entities, entity)
+		}
+		return entities
+	}
+
+	return models.CardEntities{
+		Title:       parse(card.Title),
+		Description: parse(card.Description),
+		Text:        parse(card.Text),
+	}
+}
+
+// backfillCardEntities заполняет упоминания и хештеги карточек, созданных до их появления
+func backfillCardEntities() {
+	var hasEntities bool
+	err := DB.QueryRow(`
+		SELECT EXISTS(SELECT 1 FROM card_mentions) OR EXISTS(SELECT 1 FROM card_hashtags)`).Scan(&hasEntities)
+	if err != nil {
+		log.Fatalf("Ошибка проверки упоминаний карточек: %v", err)
+	}
+	if hasEntities {
+		return
+	}
+
+	rows, err := DB.Query("SELECT id FROM cards")
+	if err != nil {
+		log.Fatalf("Ошибка получения карточек: %v", err)
+	}
+	ids := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			log.Fatalf("Ошибка получения карточек: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+
+	for _, id := range ids {
+		if err := syncCardEntities(DB, id); err != nil {
+			log.Fatalf("Ошибка заполнения упоминаний карточки %s: %v", id, err)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package db
+
+import (
+	"errors"
+	"time"
+)
+
+// FollowUser подписывает пользователя на другого пользователя
+func FollowUser(followerID, followeeID string) error {
+	if followerID == followeeID {
+		return errors.New("нельзя подписаться на самого себя")
+	}
+
+	exists, err := IsFollowing(followerID, followeeID)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return errors.New("пользователь уже подписан")
+	}
+
+	_, err = DB.Exec("INSERT INTO follows (follower_id, followee_id, created_at) VALUES (?, ?, ?)",
+		followerID, followeeID, time.Now())
+	return err
+}
+
+// UnfollowUser отменяет подписку на пользователя
+func UnfollowUser(followerID, followeeID string) error {
+	res, err := DB.Exec("DELETE FROM follows WHERE follower_id = ? AND followee_id = ?",
+		followerID, followeeID)
+	if err != nil {
+		return err
+	}
+
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return errors.New("пользователь не подписан")
+	}
+	return nil
+}
+
+// IsFollowing проверяет, подписан ли пользователь на другого пользователя
+func IsFollowing(followerID, followeeID string) (bool, error) {
+	var exists bool
+	err := DB.QueryRow("SELECT EXISTS(SELECT 1 FROM follows WHERE follower_id = ? AND followee_id = ?)",
+		followerID, followeeID).Scan(&exists)
+	return exists, err
+}
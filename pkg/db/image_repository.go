@@ -0,0 +1,127 @@
+package db
+
+import (
+	"database/sql"
+	"time"
+)
+
+// ImageRefs учитывает ссылки на файлы изображений в таблице images
+type ImageRefs struct{}
+
+// Count возвращает количество ссылок на файл изображения
+func (ImageRefs) Count(filename string) (int, error) {
+	var refs int
+	err := DB.QueryRow("SELECT refs FROM images WHERE filename = ?", filename).Scan(&refs)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return refs, err
+}
+
+// Add добавляет ссылку на файл изображения, создавая запись при первой загрузке
+func (ImageRefs) Add(filename, hash string, size int) error {
+	now := time.Now()
+	_, err := DB.Exec(`
+		INSERT INTO images (filename, hash, size, refs, created_at, referenced_at) VALUES (?, ?, ?, 1, ?, ?)
+		ON CONFLICT(filename) DO UPDATE SET refs = refs + 1, referenced_at = excluded.referenced_at`,
+		filename, hash, size, now, now)
+	return err
+}
+
+// Release убирает ссылку на файл изображения и возвращает количество оставшихся ссылок.
+// Запись без ссылок удаляется. Для файлов без записи (загруженных до учета ссылок) возвращается 0.
+func (ImageRefs) Release(filename string) (int, error) {
+	tx, err := DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var refs int
+	err = tx.QueryRow("SELECT refs FROM images WHERE filename = ?", filename).Scan(&refs)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	refs--
+	if refs > 0 {
+		_, err = tx.Exec("UPDATE images SET refs = ? WHERE filename = ?", refs, filename)
+	} else {
+		refs = 0
+		_, err = tx.Exec("DELETE FROM images WHERE filename = ?", filename)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return refs, tx.Commit()
+}
+
+// Forget удаляет запись о файле изображения, на который не осталось ссылок в карточках и профилях,
+// если ссылка на файл не добавлялась после before. Возвращает false, если файл недавно
+// загружен повторно и может вот-вот понадобиться. Время сравнивается в Go, так как sqlite хранит его строкой.
+func (ImageRefs) Forget(filename string, before time.Time) (bool, error) {
+	tx, err := DB.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var createdAt time.Time
+	var referencedAt sql.NullTime
+	err = tx.QueryRow("SELECT created_at, referenced_at FROM images WHERE filename = ?", filename).
+		Scan(&createdAt, &referencedAt)
+	if err == sql.ErrNoRows {
+		// Записи нет вовсе — файл загружен до учета ссылок
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	lastReferenced := createdAt
+	if referencedAt.Valid {
+		lastReferenced = referencedAt.Time
+	}
+	if !lastReferenced.Before(before) {
+		return false, nil
+	}
+
+	if _, err := tx.Exec("DELETE FROM images WHERE filename = ?", filename); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// GetReferencedImageFiles возвращает имена всех файлов изображений, на которые ссылаются
+// профили пользователей, карточки, их галереи и версии
+func GetReferencedImageFiles() (map[string]bool, error) {
+	rows, err := DB.Query(`
+		SELECT profile_image FROM users WHERE profile_image IS NOT NULL AND profile_image != ''
+		UNION
+		SELECT profile_banner FROM users WHERE profile_banner IS NOT NULL AND profile_banner != ''
+		UNION
+		SELECT image FROM cards WHERE image IS NOT NULL AND image != ''
+		UNION
+		SELECT filename FROM card_images
+		UNION
+		SELECT image FROM card_revisions WHERE image IS NOT NULL AND image != ''`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	files := map[string]bool{}
+	for rows.Next() {
+		var file string
+		if err := rows.Scan(&file); err != nil {
+			return nil, err
+		}
+		files[file] = true
+	}
+
+	return files, rows.Err()
+}
@@ -0,0 +1,142 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/user/roma/pkg/models"
+)
+
+// PinCard закрепляет карточку в профиле автора последней среди закрепленных
+func PinCard(cardID, userID string) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Скрытые модератором карточки закрепить нельзя
+	var pinned bool
+	err = tx.QueryRow("SELECT pin_position IS NOT NULL FROM cards WHERE id = ? AND user_id = ? AND deleted_at IS NULL AND hidden_at IS NULL",
+		cardID, userID).Scan(&pinned)
+	if err == sql.ErrNoRows {
+		return errors.New("карточку нельзя закрепить")
+	}
+	if err != nil {
+		return err
+	}
+	if pinned {
+		return errors.New("карточка уже закреплена")
+	}
+
+	// Позиции могут идти с пропусками после удаления карточек в корзину,
+	// поэтому новая карточка встает после последней закрепленной
+	var count, next int
+	err = tx.QueryRow("SELECT COUNT(*), COALESCE(MAX(pin_position) + 1, 0) FROM cards WHERE user_id = ? AND pin_position IS NOT NULL",
+		userID).Scan(&count, &next)
+	if err != nil {
+		return err
+	}
+	if count >= models.MaxPinnedCards {
+		return fmt.Errorf("можно закрепить не более %d карточек", models.MaxPinnedCards)
+	}
+
+	_, err = tx.Exec("UPDATE cards SET pin_position = ? WHERE id = ?", next, cardID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// UnpinCard открепляет карточку и сдвигает оставшиеся закрепленные карточки
+func UnpinCard(cardID, userID string) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec("UPDATE cards SET pin_position = NULL WHERE id = ? AND user_id = ? AND pin_position IS NOT NULL",
+		cardID, userID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return errors.New("карточка не закреплена")
+	}
+
+	if err := normalizePinnedCards(tx, userID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ReorderPinnedCards задает новый порядок закрепленных карточек пользователя
+func ReorderPinnedCards(userID string, cardIDs []string) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var count int
+	err = tx.QueryRow("SELECT COUNT(*) FROM cards WHERE user_id = ? AND pin_position IS NOT NULL", userID).Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count != len(cardIDs) {
+		return errors.New("порядок должен содержать все закрепленные карточки")
+	}
+
+	seen := map[string]bool{}
+	for position, id := range cardIDs {
+		if seen[id] {
+			return errors.New("карточка указана несколько раз")
+		}
+		seen[id] = true
+
+		res, err := tx.Exec("UPDATE cards SET pin_position = ? WHERE id = ? AND user_id = ? AND pin_position IS NOT NULL",
+			position, id, userID)
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err == nil && n == 0 {
+			return errors.New("закрепленная карточка не найдена")
+		}
+	}
+
+	return tx.Commit()
+}
+
+// normalizePinnedCards перенумеровывает закрепленные карточки пользователя подряд с нуля
+func normalizePinnedCards(ex execer, userID string) error {
+	rows, err := ex.Query("SELECT id FROM cards WHERE user_id = ? AND pin_position IS NOT NULL ORDER BY pin_position",
+		userID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	ids := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return err
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+
+	for position, id := range ids {
+		if _, err := ex.Exec("UPDATE cards SET pin_position = ? WHERE id = ?", position, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
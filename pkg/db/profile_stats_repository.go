@@ -0,0 +1,165 @@
+package db
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/user/roma/pkg/models"
+)
+
+// Параметры сводной статистики автора
+const (
+	ProfileStatsCacheTTL = 5 * time.Minute
+	topCardsLimit        = 5
+)
+
+// profileStatsCache хранит рассчитанную статистику авторов, чтобы повторные запросы
+// не пересчитывали ее по всем лайкам и подпискам
+var profileStatsCache = struct {
+	sync.Mutex
+	entries map[string]*models.ProfileStatsResponse
+}{entries: map[string]*models.ProfileStatsResponse{}}
+
+// GetProfileStats получает сводную статистику автора за последние days дней.
+// Результат кешируется на ProfileStatsCacheTTL.
+func GetProfileStats(userID string, days int, now time.Time) (*models.ProfileStatsResponse, error) {
+	key := fmt.Sprintf("%s:%d", userID, days)
+
+	profileStatsCache.Lock()
+	cached, ok := profileStatsCache.entries[key]
+	profileStatsCache.Unlock()
+	if ok && now.Sub(cached.GeneratedAt) < ProfileStatsCacheTTL {
+		return cached, nil
+	}
+
+	stats, err := computeProfileStats(userID, days, now)
+	if err != nil {
+		return nil, err
+	}
+
+	profileStatsCache.Lock()
+	// Удаляем устаревшие записи, чтобы кеш не рос бесконечно
+	for k, entry := range profileStatsCache.entries {
+		if now.Sub(entry.GeneratedAt) >= ProfileStatsCacheTTL {
+			delete(profileStatsCache.entries, k)
+		}
+	}
+	profileStatsCache.entries[key] = stats
+	profileStatsCache.Unlock()
+
+	return stats, nil
+}
+
+// computeProfileStats рассчитывает сводную статистику автора по карточкам, лайкам и подпискам
+func computeProfileStats(userID string, days int, now time.Time) (*models.ProfileStatsResponse, error) {
+	stats := &models.ProfileStatsResponse{
+		TopCards:    []models.TopCard{},
+		Days:        []models.ProfileStatsDay{},
+		GeneratedAt: now,
+	}
+
+	// Общее количество карточек и полученных ими лайков (без карточек в корзине)
+	err := DB.QueryRow(`
+		SELECT COUNT(*), COALESCE(SUM(likes), 0) FROM cards
+		WHERE user_id = ? AND deleted_at IS NULL`, userID).Scan(&stats.TotalCards, &stats.TotalLikes)
+	if err != nil {
+		return nil, err
+	}
+
+	// Общее количество просмотров карточек
+	err = DB.QueryRow(`
+		SELECT COALESCE(SUM(card_view_stats.views), 0) FROM card_view_stats
+		JOIN cards ON cards.id = card_view_stats.card_id
+		WHERE cards.user_id = ? AND cards.deleted_at IS NULL`, userID).Scan(&stats.TotalViews)
+	if err != nil {
+		return nil, err
+	}
+
+	// Самые популярные карточки
+	rows, err := DB.Query(`
+		SELECT id, title, likes FROM cards
+		WHERE user_id = ? AND deleted_at IS NULL
+		ORDER BY likes DESC, created_at DESC LIMIT ?`, userID, topCardsLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var card models.TopCard
+		if err := rows.Scan(&card.ID, &card.Title, &card.Likes); err != nil {
+			return nil, err
+		}
+		stats.TopCards = append(stats.TopCards, card)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	// Заполняем все дни периода
+	first, dates, index := statsPeriod(days, now)
+	for _, day := range dates {
+		stats.Days = append(stats.Days, models.ProfileStatsDay{Date: day})
+	}
+
+	// Лайки по дням (время сравнивается в Go, так как sqlite хранит его строкой)
+	likeRows, err := DB.Query(`
+		SELECT likes.created_at FROM likes
+		JOIN cards ON cards.id = likes.card_id
+		WHERE cards.user_id = ? AND cards.deleted_at IS NULL`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer likeRows.Close()
+
+	for likeRows.Next() {
+		var likedAt time.Time
+		if err := likeRows.Scan(&likedAt); err != nil {
+			return nil, err
+		}
+		if i, ok := index[likedAt.UTC().Format(statsDayFormat)]; ok {
+			stats.Days[i].Likes++
+		}
+	}
+	if err := likeRows.Err(); err != nil {
+		return nil, err
+	}
+	likeRows.Close()
+
+	// Рост подписчиков: отписки не хранятся, поэтому рост считается по текущим подписчикам
+	followRows, err := DB.Query("SELECT created_at FROM follows WHERE followee_id = ?", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer followRows.Close()
+
+	before := 0
+	for followRows.Next() {
+		var followedAt time.Time
+		if err := followRows.Scan(&followedAt); err != nil {
+			return nil, err
+		}
+		stats.Followers++
+		if followedAt.Before(first) {
+			before++
+			continue
+		}
+		if i, ok := index[followedAt.UTC().Format(statsDayFormat)]; ok {
+			stats.Days[i].NewFollowers++
+		}
+	}
+	if err := followRows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Количество подписчиков на конец каждого дня
+	total := before
+	for i := range stats.Days {
+		total += stats.Days[i].NewFollowers
+		stats.Days[i].Followers = total
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,371 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/user/roma/pkg/models"
+)
+
+// ErrReportConflict возвращается, когда жалоба уже взята в работу другим модератором или решена
+var ErrReportConflict = errors.New("жалоба уже взята в работу или решена")
+
+// ErrReportNotFound возвращается, если жалобы с таким ID нет
+var ErrReportNotFound = errors.New("жалоба не найдена")
+
+// ErrReportNotClaimed возвращается при решении по жалобе, не взятой в работу текущим модератором
+var ErrReportNotClaimed = errors.New("жалоба должна быть взята в работу текущим модератором")
+
+// ErrReportExists возвращается, если предыдущая жалоба пользователя на карточку еще не рассмотрена
+var ErrReportExists = errors.New("жалоба на эту карточку уже отправлена")
+
+// ReportError описывает неприменимое решение по жалобе (неизвестное действие,
+// удаленная карточка, автор-администратор), в отличие от ошибок базы данных
+type ReportError struct {
+	Message string
+}
+
+func (e *ReportError) Error() string {
+	return e.Message
+}
+
+// reportColumns перечисляет колонки жалобы в порядке, ожидаемом scanReport
+const reportColumns = `reports.id, reports.card_id, COALESCE(cards.title, ''), COALESCE(cards.user_id, ''),
+	reports.reporter_id, reports.reason, reports.comment, reports.content_check, reports.status, reports.claimed_by, reports.claimed_at,
+	reports.action, reports.resolution_note, reports.resolved_by, reports.resolved_at, reports.created_at`
+
+// reportSource соединяет жалобы с карточками, чтобы показать модератору заголовок и автора
+const reportSource = `reports LEFT JOIN cards ON cards.id = reports.card_id`
+
+// scanReport читает жалобу из строки результата запроса
+func scanReport(row rowScanner) (*models.Report, error) {
+	report := &models.Report{}
+	var claimedBy, action, note, resolvedBy sql.NullString
+	var claimedAt, resolvedAt sql.NullTime
+	err := row.Scan(&report.ID, &report.CardID, &report.CardTitle, &report.CardUserID,
+		&report.ReporterID, &report.Reason, &report.Comment, &report.ContentCheck, &report.Status, &claimedBy, &claimedAt,
+		&action, &note, &resolvedBy, &resolvedAt, &report.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	report.ClaimedBy = claimedBy.String
+	report.Action = action.String
+	report.ResolutionNote = note.String
+	report.ResolvedBy = resolvedBy.String
+	if claimedAt.Valid {
+		report.ClaimedAt = &claimedAt.Time
+	}
+	if resolvedAt.Valid {
+		report.ResolvedAt = &resolvedAt.Time
+	}
+	return report, nil
+}
+
+// CreateReport создает жалобу на карточку. contentCheck — правила проверки содержимого,
+// сработавшие на комментарии, для модератора. Пока предыдущая жалоба пользователя
+// на эту карточку не рассмотрена, новая не создается.
+func CreateReport(cardID, reporterID string, report models.ReportCreate, contentCheck string) (*models.Report, error) {
+	var exists bool
+	err := DB.QueryRow("SELECT EXISTS(SELECT 1 FROM reports WHERE card_id = ? AND reporter_id = ? AND status != ?)",
+		cardID, reporterID, models.ReportStatusResolved).Scan(&exists)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, ErrReportExists
+	}
+
+	id := uuid.NewString()
+	_, err = DB.Exec(`
+		INSERT INTO reports (id, card_id, reporter_id, reason, comment, content_check, status, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		id, cardID, reporterID, report.Reason, report.Comment, contentCheck, models.ReportStatusOpen, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	return GetReportByID(id)
+}
+
+// HoldCardForReview скрывает карточку до решения модератора и ставит ее в очередь
+// модерации автоматической жалобой. Если такая жалоба уже ожидает решения, новая не создается.
+func HoldCardForReview(cardID, comment string) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := hideCard(tx, cardID); err != nil {
+		return err
+	}
+
+	var exists bool
+	err = tx.QueryRow("SELECT EXISTS(SELECT 1 FROM reports WHERE card_id = ? AND reporter_id = ? AND status != ?)",
+		cardID, models.SystemReporterID, models.ReportStatusResolved).Scan(&exists)
+	if err != nil {
+		return err
+	}
+
+	if exists {
+		_, err = tx.Exec("UPDATE reports SET comment = ? WHERE card_id = ? AND reporter_id = ? AND status != ?",
+			comment, cardID, models.SystemReporterID, models.ReportStatusResolved)
+	} else {
+		_, err = tx.Exec(`
+			INSERT INTO reports (id, card_id, reporter_id, reason, comment, status, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			uuid.NewString(), cardID, models.SystemReporterID, models.ReportReasonAutomatic, comment,
+			models.ReportStatusOpen, time.Now())
+	}
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetReportByID получает жалобу по ID
+func GetReportByID(id string) (*models.Report, error) {
+	report, err := scanReport(DB.QueryRow("SELECT "+reportColumns+" FROM "+reportSource+" WHERE reports.id = ?", id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrReportNotFound
+		}
+		return nil, err
+	}
+	return report, nil
+}
+
+// GetReports получает страницу жалоб с указанным статусом, начиная с самых старых.
+// Без статуса возвращается очередь модерации: открытые и взятые в работу жалобы.
+func GetReports(status string, page, limit int) (*models.ReportsPage, error) {
+	condition, args := "reports.status != ?", []any{models.ReportStatusResolved}
+	if status != "" {
+		condition, args = "reports.status = ?", []any{status}
+	}
+
+	// Получаем общее количество жалоб
+	var totalReports int
+	err := DB.QueryRow("SELECT COUNT(*) FROM reports WHERE "+condition, args...).Scan(&totalReports)
+	if err != nil {
+		return nil, err
+	}
+
+	// Рассчитываем общее количество страниц
+	totalPages := (totalReports + limit - 1) / limit
+
+	// Если запрошенная страница больше общего количества страниц, возвращаем последнюю страницу
+	if page > totalPages && totalPages > 0 {
+		page = totalPages
+	}
+
+	// Вычисляем смещение
+	offset := (page - 1) * limit
+
+	rows, err := DB.Query("SELECT "+reportColumns+" FROM "+reportSource+" WHERE "+condition+
+		" ORDER BY reports.created_at LIMIT ? OFFSET ?", append(args, limit, offset)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reports := []models.Report{}
+	for rows.Next() {
+		report, err := scanReport(rows)
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, *report)
+	}
+
+	return &models.ReportsPage{
+		Reports:      reports,
+		Page:         page,
+		TotalPages:   totalPages,
+		TotalReports: totalReports,
+	}, rows.Err()
+}
+
+// ClaimReport берет открытую жалобу в работу модератора
+func ClaimReport(id, moderatorID string) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec("UPDATE reports SET status = ?, claimed_by = ?, claimed_at = ? WHERE id = ? AND status = ?",
+		models.ReportStatusClaimed, moderatorID, time.Now(), id, models.ReportStatusOpen)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrReportConflict
+	}
+
+	var cardID string
+	if err := tx.QueryRow("SELECT card_id FROM reports WHERE id = ?", id).Scan(&cardID); err != nil {
+		return err
+	}
+
+	if err := logModerationAction(tx, moderatorID, id, models.ModerationActionClaim, "card", cardID, ""); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ResolveReport принимает решение по жалобе, взятой в работу модератором.
+// Одобрение снова показывает карточку, скрытую проверкой содержимого. Скрытие карточки,
+// удаление карточки и блокировка автора применяются в той же транзакции, что и решение.
+// Для удаленной карточки возвращаются ее файлы: вызывающий код удаляет их после решения.
+// Решения, наказывающие карточку или автора, закрывают и остальные жалобы на эту карточку.
+func ResolveReport(id, moderatorID string, resolve models.ReportResolve) ([]string, error) {
+	var files []string
+	tx, err := DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var cardID, status string
+	var claimedBy sql.NullString
+	err = tx.QueryRow("SELECT card_id, status, claimed_by FROM reports WHERE id = ?", id).Scan(
+		&cardID, &status, &claimedBy)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrReportNotFound
+		}
+		return nil, err
+	}
+	if status != models.ReportStatusClaimed || claimedBy.String != moderatorID {
+		return nil, ErrReportNotClaimed
+	}
+
+	targetType, targetID := "card", cardID
+	switch resolve.Action {
+	case models.ModerationActionDismiss:
+	case models.ModerationActionApproveCard, models.ModerationActionHideCard, models.ModerationActionDeleteCard,
+		models.ModerationActionSuspendUser:
+		var authorID string
+		err := tx.QueryRow("SELECT user_id FROM cards WHERE id = ?", cardID).Scan(&authorID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return nil, &ReportError{Message: "карточка уже удалена"}
+			}
+			return nil, err
+		}
+
+		switch resolve.Action {
+		case models.ModerationActionApproveCard:
+			_, err = tx.Exec("UPDATE cards SET hidden_at = NULL WHERE id = ?", cardID)
+		case models.ModerationActionHideCard:
+			err = hideCard(tx, cardID)
+		case models.ModerationActionDeleteCard:
+			// Файлы собираем до удаления галереи и истории версий
+			if files, err = cardImageFiles(tx, cardID); err == nil {
+				err = deleteCard(tx, cardID)
+			}
+		case models.ModerationActionSuspendUser:
+			// Как и при блокировке из панели администратора, нельзя заблокировать
+			// администратора или себя; роль читается в той же транзакции
+			var role string
+			if err := tx.QueryRow("SELECT role FROM users WHERE id = ?", authorID).Scan(&role); err != nil {
+				return nil, err
+			}
+			if authorID == moderatorID || role == models.UserRoleAdmin {
+				return nil, &ReportError{Message: "действие нельзя применить к администратору"}
+			}
+			targetType, targetID = "user", authorID
+			err = suspendUser(tx, authorID, resolve.Note, nil)
+		}
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, &ReportError{Message: "неизвестное решение модератора"}
+	}
+
+	// Отклонение и одобрение закрывают только саму жалобу, остальные решения — все жалобы на карточку
+	condition, args := "id = ?", []any{id}
+	if resolve.Action != models.ModerationActionDismiss && resolve.Action != models.ModerationActionApproveCard {
+		condition, args = "card_id = ? AND status != ?", []any{cardID, models.ReportStatusResolved}
+	}
+	_, err = tx.Exec(`
+		UPDATE reports SET status = ?, action = ?, resolution_note = ?, resolved_by = ?, resolved_at = ?
+		WHERE `+condition,
+		append([]any{models.ReportStatusResolved, resolve.Action, resolve.Note, moderatorID, time.Now()}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := logModerationAction(tx, moderatorID, id, resolve.Action, targetType, targetID, resolve.Note); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// logModerationAction записывает действие модератора в журнал
+func logModerationAction(ex execer, moderatorID, reportID, action, targetType, targetID, note string) error {
+	_, err := ex.Exec(`
+		INSERT INTO moderation_actions (id, moderator_id, report_id, action, target_type, target_id, note, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		uuid.NewString(), moderatorID, sql.NullString{String: reportID, Valid: reportID != ""},
+		action, targetType, targetID, note, time.Now())
+	return err
+}
+
+// GetModerationLog получает страницу журнала модерации, начиная с последних действий
+func GetModerationLog(page, limit int) (*models.ModerationLogPage, error) {
+	// Получаем общее количество записей
+	var totalEntries int
+	if err := DB.QueryRow("SELECT COUNT(*) FROM moderation_actions").Scan(&totalEntries); err != nil {
+		return nil, err
+	}
+
+	// Рассчитываем общее количество страниц
+	totalPages := (totalEntries + limit - 1) / limit
+
+	// Если запрошенная страница больше общего количества страниц, возвращаем последнюю страницу
+	if page > totalPages && totalPages > 0 {
+		page = totalPages
+	}
+
+	// Вычисляем смещение
+	offset := (page - 1) * limit
+
+	rows, err := DB.Query(`
+		SELECT id, moderator_id, report_id, action, target_type, target_id, note, created_at
+		FROM moderation_actions ORDER BY created_at DESC LIMIT ? OFFSET ?`, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []models.ModerationLogEntry{}
+	for rows.Next() {
+		var entry models.ModerationLogEntry
+		var reportID sql.NullString
+		err := rows.Scan(&entry.ID, &entry.ModeratorID, &reportID, &entry.Action,
+			&entry.TargetType, &entry.TargetID, &entry.Note, &entry.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		entry.ReportID = reportID.String
+		entries = append(entries, entry)
+	}
+
+	return &models.ModerationLogPage{
+		Entries:      entries,
+		Page:         page,
+		TotalPages:   totalPages,
+		TotalEntries: totalEntries,
+	}, rows.Err()
+}
@@ -0,0 +1,235 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/user/roma/pkg/models"
+	"github.com/user/roma/pkg/utils"
+)
+
+// RepostCard добавляет репост карточки в ленту пользователя
+func RepostCard(cardID, userID string) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Проверяем, репостнул ли уже пользователь карточку
+	var exists bool
+	err = tx.QueryRow("SELECT EXISTS(SELECT 1 FROM reposts WHERE user_id = ? AND card_id = ?)",
+		userID, cardID).Scan(&exists)
+	if err != nil {
+		return err
+	}
+
+	if exists {
+		return errors.New("пользователь уже репостнул эту карточку")
+	}
+
+	// Добавляем запись о репосте
+	_, err = tx.Exec("INSERT INTO reposts (user_id, card_id, created_at) VALUES (?, ?, ?)",
+		userID, cardID, time.Now())
+	if err != nil {
+		return err
+	}
+
+	// Увеличиваем счетчик репостов
+	_, err = tx.Exec("UPDATE cards SET reposts = reposts + 1 WHERE id = ?", cardID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// UnrepostCard отменяет репост карточки
+func UnrepostCard(cardID, userID string) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Удаляем запись о репосте
+	res, err := tx.Exec("DELETE FROM reposts WHERE user_id = ? AND card_id = ?", userID, cardID)
+	if err != nil {
+		return err
+	}
+
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return errors.New("пользователь не репостил эту карточку")
+	}
+
+	// Уменьшаем счетчик репостов
+	_, err = tx.Exec("UPDATE cards SET reposts = reposts - 1 WHERE id = ?", cardID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetUserCards получает ленту пользователя с пагинацией: его собственные карточки и репосты.
+// Закрепленные карточки идут первыми в порядке закрепления. Репосты показываются только
+// для карточек, которые текущий пользователь видит в списках, поэтому удаленные и скрытые
+// оригиналы пропадают из ленты автоматически. Лента заблокированного или скрытого
+// пользователя, как и его карточки, видна только ему самому.
+func GetUserCards(userID string, page, limit int, currentUserID string) (*models.PaginationResponse, error) {
+	hidden, err := hiddenAuthorIDs()
+	if err != nil {
+		return nil, err
+	}
+	listed, listedArgs := listedCardsCondition(currentUserID, hidden)
+
+	// Автор видит все свои карточки, кроме удаленных, остальные — только видимые им опубликованные
+	condition, args := "user_id = ? AND deleted_at IS NULL", []any{userID}
+	if currentUserID != userID {
+		condition += " AND " + listed
+		args = append(args, listedArgs...)
+	}
+
+	source := `SELECT id, 0 AS is_repost, pin_position, created_at AS sort_at FROM cards WHERE ` + condition
+	if currentUserID == userID || !slices.Contains(hidden, userID) {
+		source += `
+		UNION ALL
+		SELECT id, 1, NULL, reposted_at FROM (
+			SELECT cards.*, reposts.created_at AS reposted_at FROM reposts
+			JOIN cards ON cards.id = reposts.card_id
+			WHERE reposts.user_id = ?
+		) WHERE ` + listed
+		args = append(args, userID)
+		args = append(args, listedArgs...)
+	}
+
+	// Получаем общее количество записей ленты
+	var totalCards int
+	err = DB.QueryRow("SELECT COUNT(*) FROM ("+source+")", args...).Scan(&totalCards)
+	if err != nil {
+		return nil, err
+	}
+
+	// Рассчитываем общее количество страниц
+	totalPages := (totalCards + limit - 1) / limit
+
+	// Если запрошенная страница больше общего количества страниц, возвращаем последнюю страницу
+	if page > totalPages && totalPages > 0 {
+		page = totalPages
+	}
+
+	// Вычисляем смещение
+	offset := (page - 1) * limit
+
+	// Получаем записи ленты для текущей страницы
+	rows, err := DB.Query("SELECT id, is_repost FROM ("+source+") ORDER BY pin_position IS NULL, pin_position, sort_at DESC LIMIT ? OFFSET ?",
+		append(args, limit, offset)...)
+	if err != nil {
+		return nil, err
+	}
+
+	type timelineEntry struct {
+		cardID   string
+		isRepost bool
+	}
+	entries := []timelineEntry{}
+	for rows.Next() {
+		var entry timelineEntry
+		if err := rows.Scan(&entry.cardID, &entry.isRepost); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var reposter *models.User
+	cardResponses := []models.CardResponse{}
+	for _, entry := range entries {
+		card, err := scanCard(DB.QueryRow("SELECT "+cardColumns+" FROM cards WHERE id = ?", entry.cardID))
+		if err != nil {
+			return nil, err
+		}
+
+		response, err := listCardResponse(card, currentUserID)
+		if err != nil {
+			return nil, err
+		}
+
+		// Для репостов указываем, кто и когда поделился карточкой.
+		// Закрепление относится к профилю автора, поэтому у репоста оно не показывается.
+		if entry.isRepost {
+			response.IsPinned = false
+
+			if reposter == nil {
+				reposter, err = GetUserByID(userID)
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			var repostedAt time.Time
+			err = DB.QueryRow("SELECT created_at FROM reposts WHERE user_id = ? AND card_id = ?",
+				userID, card.ID).Scan(&repostedAt)
+			if err != nil {
+				return nil, err
+			}
+
+			response.RepostedBy = &models.RepostInfo{
+				UserID:     reposter.ID,
+				UserName:   reposter.Login,
+				RepostedAt: repostedAt,
+			}
+		}
+
+		cardResponses = append(cardResponses, response)
+	}
+
+	return &models.PaginationResponse{
+		Cards:      cardResponses,
+		Page:       page,
+		TotalPages: totalPages,
+		TotalCards: totalCards,
+	}, nil
+}
+
+// ToQuotedCard формирует цитируемую карточку для пользователя.
+// Если оригинал удален или не виден пользователю, возвращается только его ID.
+func ToQuotedCard(quoteCardID, currentUserID string) (*models.QuotedCard, error) {
+	if quoteCardID == "" {
+		return nil, nil
+	}
+
+	quoted := &models.QuotedCard{ID: quoteCardID}
+
+	card, err := scanCard(DB.QueryRow("SELECT "+cardColumns+" FROM cards WHERE id = ? AND deleted_at IS NULL", quoteCardID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return quoted, nil
+		}
+		return nil, err
+	}
+
+	visible, err := CanViewCard(card, currentUserID)
+	if err != nil {
+		return nil, err
+	}
+	if !visible {
+		return quoted, nil
+	}
+
+	quoted.Available = true
+	quoted.UserID = card.UserID
+	quoted.UserName = card.UserName
+	quoted.Title = card.Title
+	quoted.Description = card.Description
+	quoted.CreatedAt = &card.CreatedAt
+	quoted.Image = utils.ImageURL(card.Image)
+
+	return quoted, nil
+}
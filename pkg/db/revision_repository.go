@@ -0,0 +1,127 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/user/roma/pkg/models"
+)
+
+// execer объединяет *sql.DB и *sql.Tx
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// createRevision сохраняет текущее состояние карточки как новую версию
+func createRevision(ex execer, cardID string) error {
+	var next int
+	err := ex.QueryRow("SELECT COALESCE(MAX(revision), 0) + 1 FROM card_revisions WHERE card_id = ?",
+		cardID).Scan(&next)
+	if err != nil {
+		return err
+	}
+
+	_, err = ex.Exec(`
+		INSERT INTO card_revisions (id, card_id, revision, image, title, description, text, created_at)
+		SELECT ?, id, ?, image, title, description, text, ? FROM cards WHERE id = ?`,
+		uuid.NewString(), next, time.Now(), cardID)
+	return err
+}
+
+// ensureBaselineRevision сохраняет исходное состояние карточки, созданной до появления истории версий
+func ensureBaselineRevision(ex execer, cardID string) error {
+	var exists bool
+	err := ex.QueryRow("SELECT EXISTS(SELECT 1 FROM card_revisions WHERE card_id = ?)", cardID).Scan(&exists)
+	if err != nil || exists {
+		return err
+	}
+	return createRevision(ex, cardID)
+}
+
+// GetCardRevisions получает все версии карточки, начиная с последней
+func GetCardRevisions(cardID string) ([]models.CardRevision, error) {
+	rows, err := DB.Query(`
+		SELECT id, card_id, revision, image, title, description, text, created_at
+		FROM card_revisions WHERE card_id = ? ORDER BY revision DESC`, cardID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := []models.CardRevision{}
+	for rows.Next() {
+		revision, err := scanRevision(rows)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, *revision)
+	}
+
+	return revisions, rows.Err()
+}
+
+// GetCardRevision получает версию карточки по номеру
+func GetCardRevision(cardID string, revision int) (*models.CardRevision, error) {
+	rev, err := scanRevision(DB.QueryRow(`
+		SELECT id, card_id, revision, image, title, description, text, created_at
+		FROM card_revisions WHERE card_id = ? AND revision = ?`, cardID, revision))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New("версия карточки не найдена")
+		}
+		return nil, err
+	}
+	return rev, nil
+}
+
+// RestoreCardRevision возвращает карточку к указанной версии и сохраняет результат как новую версию
+func RestoreCardRevision(cardID string, revision *models.CardRevision) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		UPDATE cards
+		SET title = ?, description = ?, text = ?, updated_at = ?
+		WHERE id = ?`,
+		revision.Title, revision.Description, revision.Text, time.Now(), cardID)
+	if err != nil {
+		return err
+	}
+
+	// Изображение версии снова становится обложкой галереи
+	if err := setCardCover(tx, cardID, revision.Image); err != nil {
+		return err
+	}
+
+	if err := syncCardEntities(tx, cardID); err != nil {
+		return err
+	}
+
+	if err := createRevision(tx, cardID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// scanRevision читает версию карточки из строки результата запроса
+func scanRevision(row rowScanner) (*models.CardRevision, error) {
+	revision := &models.CardRevision{}
+	var image, description, text sql.NullString
+	err := row.Scan(&revision.ID, &revision.CardID, &revision.Revision, &image,
+		&revision.Title, &description, &text, &revision.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	revision.Image = image.String
+	revision.Description = description.String
+	revision.Text = text.String
+	return revision, nil
+}
@@ -0,0 +1,205 @@
+package db
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/user/roma/pkg/models"
+)
+
+// statsDayFormat задает формат дня в дневной статистике (дни считаются в UTC)
+const statsDayFormat = "2006-01-02"
+
+// statsPeriod возвращает первый день периода из days дней, заканчивающегося текущим днем,
+// список дат периода и индекс даты в этом списке
+func statsPeriod(days int, now time.Time) (time.Time, []string, map[string]int) {
+	today := now.UTC().Truncate(24 * time.Hour)
+	first := today.AddDate(0, 0, -(days - 1))
+
+	dates := make([]string, 0, days)
+	index := map[string]int{}
+	for i := 0; i < days; i++ {
+		day := first.AddDate(0, 0, i).Format(statsDayFormat)
+		index[day] = i
+		dates = append(dates, day)
+	}
+	return first, dates, index
+}
+
+// RecordCardViews сохраняет пачку просмотров в дневную статистику.
+// Повторный просмотр того же зрителя в пределах окна дедупликации не учитывается.
+func RecordCardViews(views []models.CardView, window time.Duration) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, view := range views {
+		// Проверяем, когда зритель последний раз засчитал просмотр этой карточки
+		var lastViewedAt time.Time
+		err := tx.QueryRow("SELECT last_viewed_at FROM card_viewers WHERE card_id = ? AND viewer_key = ?",
+			view.CardID, view.ViewerKey).Scan(&lastViewedAt)
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+		if err == nil && view.ViewedAt.Sub(lastViewedAt) < window {
+			continue
+		}
+
+		_, err = tx.Exec(`
+			INSERT INTO card_viewers (card_id, viewer_key, last_viewed_at) VALUES (?, ?, ?)
+			ON CONFLICT(card_id, viewer_key) DO UPDATE SET last_viewed_at = excluded.last_viewed_at`,
+			view.CardID, view.ViewerKey, view.ViewedAt)
+		if err != nil {
+			return err
+		}
+
+		// Зритель считается уникальным один раз за день
+		day := view.ViewedAt.UTC().Format(statsDayFormat)
+		res, err := tx.Exec("INSERT OR IGNORE INTO card_daily_viewers (card_id, day, viewer_key) VALUES (?, ?, ?)",
+			view.CardID, day, view.ViewerKey)
+		if err != nil {
+			return err
+		}
+		unique, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(`
+			INSERT INTO card_view_stats (card_id, day, views, unique_viewers) VALUES (?, ?, 1, ?)
+			ON CONFLICT(card_id, day) DO UPDATE SET
+				views = views + 1,
+				unique_viewers = unique_viewers + excluded.unique_viewers`,
+			view.CardID, day, unique)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// PruneCardViewers удаляет записи о зрителях, которые больше не нужны для дедупликации:
+// последние просмотры старше viewedBefore и дневных зрителей за дни раньше дня dayBefore (UTC).
+// Время последнего просмотра сравнивается в Go, так как sqlite хранит его строкой.
+// Вызывается из того же обработчика, что и RecordCardViews, поэтому записи не меняются между чтением и удалением.
+func PruneCardViewers(viewedBefore, dayBefore time.Time) (int, error) {
+	rows, err := DB.Query("SELECT card_id, viewer_key, last_viewed_at FROM card_viewers")
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	type viewer struct{ cardID, key string }
+	expired := []viewer{}
+	for rows.Next() {
+		var v viewer
+		var lastViewedAt time.Time
+		if err := rows.Scan(&v.cardID, &v.key, &lastViewedAt); err != nil {
+			return 0, err
+		}
+		if lastViewedAt.Before(viewedBefore) {
+			expired = append(expired, v)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	rows.Close()
+
+	tx, err := DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	for _, v := range expired {
+		if _, err := tx.Exec("DELETE FROM card_viewers WHERE card_id = ? AND viewer_key = ?", v.cardID, v.key); err != nil {
+			return 0, err
+		}
+	}
+
+	// Дни хранятся строками в формате ГГГГ-ММ-ДД, поэтому их можно сравнивать в SQL
+	res, err := tx.Exec("DELETE FROM card_daily_viewers WHERE day < ?", dayBefore.UTC().Format(statsDayFormat))
+	if err != nil {
+		return 0, err
+	}
+	days, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return len(expired) + int(days), nil
+}
+
+// GetCardStats получает статистику карточки: общие показатели и разбивку
+// по дням за последние days дней, включая текущий
+func GetCardStats(card *models.Card, days int, now time.Time) (*models.CardStatsResponse, error) {
+	stats := &models.CardStatsResponse{
+		CardID: card.ID,
+		Likes:  card.Likes,
+		Days:   []models.CardStatsDay{},
+	}
+
+	// Общее количество просмотров и уникальных зрителей за все время. Зрители хранятся
+	// только для дедупликации и удаляются, поэтому уникальные зрители суммируются по дням.
+	err := DB.QueryRow("SELECT COALESCE(SUM(views), 0), COALESCE(SUM(unique_viewers), 0) FROM card_view_stats WHERE card_id = ?",
+		card.ID).Scan(&stats.Views, &stats.UniqueViewers)
+	if err != nil {
+		return nil, err
+	}
+
+	// Заполняем все дни периода, чтобы в графике не было пропусков
+	first, dates, index := statsPeriod(days, now)
+	for _, day := range dates {
+		stats.Days = append(stats.Days, models.CardStatsDay{Date: day})
+	}
+
+	// Просмотры по дням
+	rows, err := DB.Query("SELECT day, views, unique_viewers FROM card_view_stats WHERE card_id = ? AND day >= ?",
+		card.ID, first.Format(statsDayFormat))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var day string
+		var views, uniqueViewers int
+		if err := rows.Scan(&day, &views, &uniqueViewers); err != nil {
+			return nil, err
+		}
+		if i, ok := index[day]; ok {
+			stats.Days[i].Views = views
+			stats.Days[i].UniqueViewers = uniqueViewers
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	// Лайки по дням (время лайка сравнивается в Go, так как sqlite хранит его строкой)
+	likeRows, err := DB.Query("SELECT created_at FROM likes WHERE card_id = ?", card.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer likeRows.Close()
+
+	for likeRows.Next() {
+		var likedAt time.Time
+		if err := likeRows.Scan(&likedAt); err != nil {
+			return nil, err
+		}
+		if i, ok := index[likedAt.UTC().Format(statsDayFormat)]; ok {
+			stats.Days[i].Likes++
+		}
+	}
+
+	return stats, likeRows.Err()
+}
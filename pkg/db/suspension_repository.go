@@ -0,0 +1,148 @@
+package db
+
+import (
+	"errors"
+	"time"
+
+	"github.com/user/roma/pkg/models"
+)
+
+// suspendUser блокирует пользователя с указанием причины до указанного времени (nil — бессрочно)
+func suspendUser(ex execer, userID, reason string, until *time.Time) error {
+	_, err := ex.Exec("UPDATE users SET suspended_at = ?, suspended_until = ?, suspend_reason = ?, updated_at = ? WHERE id = ?",
+		time.Now(), until, reason, time.Now(), userID)
+	return err
+}
+
+// SuspendUser блокирует пользователя по решению администратора и записывает действие в журнал
+func SuspendUser(moderatorID, userID string, suspend models.UserSuspend) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := suspendUser(tx, userID, suspend.Reason, suspend.Until); err != nil {
+		return err
+	}
+
+	err = logModerationAction(tx, moderatorID, "", models.ModerationActionSuspendUser, "user", userID, suspend.Reason)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// LiftSuspension снимает блокировку пользователя и записывает действие в журнал
+func LiftSuspension(moderatorID, userID, note string) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`
+		UPDATE users SET suspended_at = NULL, suspended_until = NULL, suspend_reason = NULL, updated_at = ?
+		WHERE id = ? AND suspended_at IS NOT NULL`, time.Now(), userID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return errors.New("пользователь не заблокирован")
+	}
+
+	err = logModerationAction(tx, moderatorID, "", models.ModerationActionUnsuspendUser, "user", userID, note)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ShadowBanUser скрывает контент пользователя от всех, кроме него самого
+func ShadowBanUser(moderatorID, userID, note string) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec("UPDATE users SET shadow_banned_at = ?, updated_at = ? WHERE id = ? AND shadow_banned_at IS NULL",
+		time.Now(), time.Now(), userID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return errors.New("контент пользователя уже скрыт")
+	}
+
+	err = logModerationAction(tx, moderatorID, "", models.ModerationActionShadowBanUser, "user", userID, note)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// LiftShadowBan снова делает контент пользователя видимым
+func LiftShadowBan(moderatorID, userID, note string) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec("UPDATE users SET shadow_banned_at = NULL, updated_at = ? WHERE id = ? AND shadow_banned_at IS NOT NULL",
+		time.Now(), userID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return errors.New("контент пользователя не скрыт")
+	}
+
+	err = logModerationAction(tx, moderatorID, "", models.ModerationActionUnshadowBanUser, "user", userID, note)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// LiftExpiredSuspensions снимает блокировки, срок которых истек к указанному времени.
+// Время сравнивается в Go, так как sqlite хранит его строкой.
+func LiftExpiredSuspensions(now time.Time) (int, error) {
+	rows, err := DB.Query("SELECT id, suspended_until FROM users WHERE suspended_at IS NOT NULL AND suspended_until IS NOT NULL")
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	expired := []string{}
+	for rows.Next() {
+		var id string
+		var until time.Time
+		if err := rows.Scan(&id, &until); err != nil {
+			return 0, err
+		}
+		if !until.After(now) {
+			expired = append(expired, id)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	rows.Close()
+
+	for _, id := range expired {
+		_, err := DB.Exec(`
+			UPDATE users SET suspended_at = NULL, suspended_until = NULL, suspend_reason = NULL, updated_at = ?
+			WHERE id = ?`, now, id)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return len(expired), nil
+}
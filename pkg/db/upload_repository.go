@@ -0,0 +1,103 @@
+package db
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/user/roma/pkg/models"
+)
+
+// CreateUpload создает запись о возобновляемой загрузке
+func CreateUpload(userID string, length int64, metadata string, expiresAt time.Time) (*models.Upload, error) {
+	upload := &models.Upload{
+		ID:        uuid.NewString(),
+		UserID:    userID,
+		Length:    length,
+		Metadata:  metadata,
+		CreatedAt: time.Now(),
+		ExpiresAt: expiresAt,
+	}
+
+	_, err := DB.Exec(`
+		INSERT INTO uploads (id, user_id, length, metadata, created_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		upload.ID, upload.UserID, upload.Length, upload.Metadata, upload.CreatedAt, upload.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return upload, nil
+}
+
+// GetUpload возвращает загрузку по ID. Смещение заполняет вызывающий код по размеру файла.
+func GetUpload(id string) (*models.Upload, error) {
+	upload := &models.Upload{}
+	err := DB.QueryRow(`
+		SELECT id, user_id, length, metadata, created_at, expires_at FROM uploads WHERE id = ?`, id).
+		Scan(&upload.ID, &upload.UserID, &upload.Length, &upload.Metadata, &upload.CreatedAt, &upload.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	return upload, nil
+}
+
+// CountActiveUploads возвращает количество неистекших загрузок пользователя
+func CountActiveUploads(userID string) (int, error) {
+	rows, err := DB.Query("SELECT expires_at FROM uploads WHERE user_id = ?", userID)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	// Сравниваем время в Go, так как SQLite хранит его строкой
+	now := time.Now()
+	count := 0
+	for rows.Next() {
+		var expiresAt time.Time
+		if err := rows.Scan(&expiresAt); err != nil {
+			return 0, err
+		}
+		if expiresAt.After(now) {
+			count++
+		}
+	}
+
+	return count, rows.Err()
+}
+
+// DeleteUpload удаляет запись о загрузке
+func DeleteUpload(id string) error {
+	_, err := DB.Exec("DELETE FROM uploads WHERE id = ?", id)
+	return err
+}
+
+// GetExpiredUploadIDs возвращает ID загрузок, срок которых истек до before
+func GetExpiredUploadIDs(before time.Time) ([]string, error) {
+	rows, err := DB.Query("SELECT id, expires_at FROM uploads")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	// Сравниваем время в Go, так как SQLite хранит его строкой
+	ids := []string{}
+	for rows.Next() {
+		var id string
+		var expiresAt time.Time
+		if err := rows.Scan(&id, &expiresAt); err != nil {
+			return nil, err
+		}
+		if !expiresAt.After(before) {
+			ids = append(ids, id)
+		}
+	}
+
+	return ids, rows.Err()
+}
+
+// UploadExists проверяет, есть ли запись о загрузке
+func UploadExists(id string) (bool, error) {
+	var exists bool
+	err := DB.QueryRow("SELECT EXISTS(SELECT 1 FROM uploads WHERE id = ?)", id).Scan(&exists)
+	return exists, err
+}
@@ -3,10 +3,12 @@ package db
 import (
 	"database/sql"
 	"errors"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/user/roma/pkg/models"
+	"github.com/user/roma/pkg/utils"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -35,6 +37,7 @@ func CreateUser(user models.UserRegister) (*models.User, error) {
 		Login:     user.Login,
 		Email:     user.Email,
 		Password:  string(hashedPassword),
+		Role:      models.UserRoleUser,
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 	}
@@ -52,21 +55,22 @@ func CreateUser(user models.UserRegister) (*models.User, error) {
 	return newUser, nil
 }
 
-// GetUserByID получает пользователя по ID
-func GetUserByID(id string) (*models.User, error) {
+// userColumns перечисляет колонки пользователя в порядке, ожидаемом scanUser
+const userColumns = `id, login, email, password, profile_image, profile_banner, description, role,
+	suspended_at, suspended_until, suspend_reason, shadow_banned_at, created_at, updated_at`
+
+// scanUser читает пользователя из строки результата запроса
+func scanUser(row rowScanner) (*models.User, error) {
 	user := &models.User{}
-	var profileImage, profileBanner, description sql.NullString
+	var profileImage, profileBanner, description, suspendReason sql.NullString
+	var suspendedAt, suspendedUntil, shadowBannedAt sql.NullTime
 
-	err := DB.QueryRow(`
-		SELECT id, login, email, password, profile_image, profile_banner, description, created_at, updated_at 
-		FROM users WHERE id = ?`, id).Scan(
+	err := row.Scan(
 		&user.ID, &user.Login, &user.Email, &user.Password,
-		&profileImage, &profileBanner, &description,
+		&profileImage, &profileBanner, &description, &user.Role,
+		&suspendedAt, &suspendedUntil, &suspendReason, &shadowBannedAt,
 		&user.CreatedAt, &user.UpdatedAt)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, errors.New("пользователь не найден")
-		}
 		return nil, err
 	}
 
@@ -80,23 +84,37 @@ func GetUserByID(id string) (*models.User, error) {
 	if description.Valid {
 		user.Description = description.String
 	}
+	if suspendedAt.Valid {
+		user.SuspendedAt = &suspendedAt.Time
+		user.SuspendReason = suspendReason.String
+	}
+	if suspendedUntil.Valid {
+		user.SuspendedUntil = &suspendedUntil.Time
+	}
+	if shadowBannedAt.Valid {
+		user.ShadowBannedAt = &shadowBannedAt.Time
+	}
+
+	return user, nil
+}
+
+// GetUserByID получает пользователя по ID
+func GetUserByID(id string) (*models.User, error) {
+	user, err := scanUser(DB.QueryRow("SELECT "+userColumns+" FROM users WHERE id = ?", id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New("пользователь не найден")
+		}
+		return nil, err
+	}
 
 	return user, nil
 }
 
 // GetUserByCredentials получает пользователя по логину/email и паролю
 func GetUserByCredentials(login, email, password string) (*models.User, error) {
-	user := &models.User{}
-	var hashedPassword string
-	var profileImage, profileBanner, description sql.NullString
-
 	// Пытаемся найти пользователя по логину или email
-	err := DB.QueryRow(`
-		SELECT id, login, email, password, profile_image, profile_banner, description, created_at, updated_at 
-		FROM users WHERE login = ? OR email = ?`, login, email).Scan(
-		&user.ID, &user.Login, &user.Email, &hashedPassword,
-		&profileImage, &profileBanner, &description,
-		&user.CreatedAt, &user.UpdatedAt)
+	user, err := scanUser(DB.QueryRow("SELECT "+userColumns+" FROM users WHERE login = ? OR email = ?", login, email))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, errors.New("пользователь не найден")
@@ -105,23 +123,11 @@ func GetUserByCredentials(login, email, password string) (*models.User, error) {
 	}
 
 	// Проверка пароля
-	err = bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
+	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
 	if err != nil {
 		return nil, errors.New("неверный пароль")
 	}
 
-	// Преобразуем sql.NullString в строки
-	if profileImage.Valid {
-		user.ProfileImage = profileImage.String
-	}
-	if profileBanner.Valid {
-		user.ProfileBanner = profileBanner.String
-	}
-	if description.Valid {
-		user.Description = description.String
-	}
-
-	user.Password = hashedPassword
 	return user, nil
 }
 
@@ -135,27 +141,29 @@ func UpdateUserProfile(userID string, profileImage, profileBanner, description s
 	return err
 }
 
-// ToUserResponse преобразует User в UserResponse
-func ToUserResponse(user *models.User) models.UserResponse {
-	// Базовый URL для изображений
-	baseURL := "http://localhost:3000/uploads/" // Порт берется из конфигурации сервера
-
-	// Формируем полные URL для изображений
-	var profileImage, profileBanner string
-	if user.ProfileImage != "" {
-		profileImage = baseURL + user.ProfileImage
-	}
-	if user.ProfileBanner != "" {
-		profileBanner = baseURL + user.ProfileBanner
+// GrantAdminRoles назначает роль администратора пользователям с указанными логинами
+func GrantAdminRoles(logins []string) error {
+	for _, login := range logins {
+		_, err := DB.Exec("UPDATE users SET role = ? WHERE login = ?", models.UserRoleAdmin, strings.TrimSpace(login))
+		if err != nil {
+			return err
+		}
 	}
+	return nil
+}
 
+// ToUserResponse преобразует User в UserResponse
+func ToUserResponse(user *models.User) models.UserResponse {
 	return models.UserResponse{
-		ID:            user.ID,
-		Login:         user.Login,
-		Email:         user.Email,
-		ProfileImage:  profileImage,
-		ProfileBanner: profileBanner,
-		Description:   user.Description,
-		CreatedAt:     user.CreatedAt,
+		ID:                  user.ID,
+		Login:               user.Login,
+		Email:               user.Email,
+		ProfileImage:        utils.ImageURL(user.ProfileImage),
+		ProfileImageSrcSet:  utils.ImageSrcSet(user.ProfileImage),
+		ProfileBanner:       utils.ImageURL(user.ProfileBanner),
+		ProfileBannerSrcSet: utils.ImageSrcSet(user.ProfileBanner),
+		Description:         user.Description,
+		Role:                user.Role,
+		CreatedAt:           user.CreatedAt,
 	}
 }
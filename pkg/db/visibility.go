@@ -0,0 +1,105 @@
+package db
+
+import (
+	"database/sql"
+	"strings"
+
+	"github.com/user/roma/pkg/models"
+)
+
+// hiddenAuthorIDs возвращает ID заблокированных и скрытых (shadow-ban) пользователей.
+// Срок блокировки проверяется в Go тем же User.IsSuspended, что и в CanViewCard,
+// так как sqlite хранит время строкой: истекшая блокировка не скрывает карточки,
+// даже если ее еще не снял LiftExpiredSuspensions.
+func hiddenAuthorIDs() ([]string, error) {
+	rows, err := DB.Query(`
+		SELECT id, suspended_at, suspended_until, shadow_banned_at FROM users
+		WHERE suspended_at IS NOT NULL OR shadow_banned_at IS NOT NULL`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	hidden := []string{}
+	for rows.Next() {
+		var user models.User
+		var suspendedAt, suspendedUntil, shadowBannedAt sql.NullTime
+		if err := rows.Scan(&user.ID, &suspendedAt, &suspendedUntil, &shadowBannedAt); err != nil {
+			return nil, err
+		}
+		if suspendedAt.Valid {
+			user.SuspendedAt = &suspendedAt.Time
+		}
+		if suspendedUntil.Valid {
+			user.SuspendedUntil = &suspendedUntil.Time
+		}
+		if shadowBannedAt.Valid {
+			user.ShadowBannedAt = &shadowBannedAt.Time
+		}
+		if user.IsSuspended() || user.IsShadowBanned() {
+			hidden = append(hidden, user.ID)
+		}
+	}
+	return hidden, rows.Err()
+}
+
+// listedCardsCondition возвращает условие WHERE для карточек, которые пользователь
+// может видеть в списках: не удаленные и не скрытые модератором опубликованные публичные,
+// карточки для подписчиков авторов, на которых он подписан, и собственные приватные карточки.
+// Карточки по ссылке (unlisted) в списки не попадают. Карточки авторов из hiddenAuthors
+// (см. hiddenAuthorIDs) видны только самим авторам.
+func listedCardsCondition(currentUserID string, hiddenAuthors []string) (string, []any) {
+	condition := `deleted_at IS NULL AND hidden_at IS NULL AND status = ? AND (
+		visibility = ?
+		OR (visibility = ? AND (user_id = ? OR user_id IN (SELECT followee_id FROM follows WHERE follower_id = ?)))
+		OR (visibility = ? AND user_id = ?))`
+	args := []any{
+		models.CardStatusPublished,
+		models.CardVisibilityPublic,
+		models.CardVisibilityFollowers, currentUserID, currentUserID,
+		models.CardVisibilityPrivate, currentUserID,
+	}
+
+	if len(hiddenAuthors) > 0 {
+		condition += " AND (user_id = ? OR user_id NOT IN (?" + strings.Repeat(", ?", len(hiddenAuthors)-1) + "))"
+		args = append(args, currentUserID)
+		for _, id := range hiddenAuthors {
+			args = append(args, id)
+		}
+	}
+	return condition, args
+}
+
+// CanViewCard проверяет, может ли пользователь открыть карточку по прямой ссылке
+func CanViewCard(card *models.Card, userID string) (bool, error) {
+	// Автор видит свои карточки всегда
+	if userID != "" && card.UserID == userID {
+		return true, nil
+	}
+
+	// Неопубликованные и скрытые модератором карточки доступны только автору
+	if !card.IsPublished() || card.HiddenAt != nil {
+		return false, nil
+	}
+
+	// Карточки заблокированных и скрытых авторов видны только самим авторам
+	author, err := GetUserByID(card.UserID)
+	if err != nil {
+		return false, err
+	}
+	if author.IsSuspended() || author.IsShadowBanned() {
+		return false, nil
+	}
+
+	switch card.Visibility {
+	case models.CardVisibilityPublic, models.CardVisibilityUnlisted:
+		return true, nil
+	case models.CardVisibilityFollowers:
+		if userID == "" {
+			return false, nil
+		}
+		return IsFollowing(userID, card.UserID)
+	default:
+		return false, nil
+	}
+}
@@ -0,0 +1,42 @@
+package jobs
+
+import (
+	"log"
+	"os"
+	"time"
+
+	"github.com/user/roma/pkg/contentcheck"
+)
+
+// ContentRulesReloadInterval определяет, как часто проверяется изменение файла правил
+const ContentRulesReloadInterval = 30 * time.Second
+
+// ContentRulesFile возвращает путь к файлу правил проверки содержимого из переменной
+// окружения CONTENT_RULES_FILE или путь по умолчанию
+func ContentRulesFile() string {
+	if path := os.Getenv("CONTENT_RULES_FILE"); path != "" {
+		return path
+	}
+	return contentcheck.DefaultRulesFile
+}
+
+// StartContentRulesReloader загружает правила проверки содержимого и затем периодически
+// перечитывает файл, если он изменился, чтобы правила применялись без перезапуска сервера.
+// Если файл не удалось загрузить, проверка работает с последними загруженными правилами.
+func StartContentRulesReloader(path string, interval time.Duration) {
+	if err := contentcheck.Load(path); err != nil {
+		log.Printf("Правила проверки содержимого не загружены из %s: %v", path, err)
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			// Отсутствие файла уже записано в лог при запуске
+			if err := contentcheck.ReloadIfChanged(path); err != nil && !os.IsNotExist(err) {
+				log.Printf("Ошибка перезагрузки правил проверки содержимого: %v", err)
+			}
+		}
+	}()
+}
@@ -0,0 +1,36 @@
+package jobs
+
+import (
+	"log"
+	"time"
+
+	"github.com/user/roma/pkg/db"
+)
+
+// PublishInterval определяет, как часто проверяются запланированные карточки
+const PublishInterval = time.Minute
+
+// StartCardPublisher запускает фоновую публикацию запланированных карточек
+func StartCardPublisher(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			publishDueCards()
+			<-ticker.C
+		}
+	}()
+}
+
+// publishDueCards публикует карточки, время публикации которых наступило
+func publishDueCards() {
+	published, err := db.PublishDueCards(time.Now())
+	if err != nil {
+		log.Printf("Ошибка публикации запланированных карточек: %v", err)
+		return
+	}
+	if published > 0 {
+		log.Printf("Опубликовано запланированных карточек: %d", published)
+	}
+}
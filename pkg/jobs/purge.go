@@ -0,0 +1,80 @@
+package jobs
+
+import (
+	"log"
+	"os"
+	"time"
+
+	"github.com/user/roma/pkg/db"
+	"github.com/user/roma/pkg/utils"
+)
+
+// Параметры очистки корзины по умолчанию
+const (
+	PurgeInterval         = time.Hour
+	DefaultTrashRetention = 30 * 24 * time.Hour
+)
+
+// TrashRetention возвращает срок хранения карточек в корзине из переменной
+// окружения CARD_TRASH_RETENTION (например, "720h") или значение по умолчанию
+func TrashRetention() time.Duration {
+	value := os.Getenv("CARD_TRASH_RETENTION")
+	if value == "" {
+		return DefaultTrashRetention
+	}
+
+	retention, err := time.ParseDuration(value)
+	if err != nil || retention < 0 {
+		log.Printf("Неверное значение CARD_TRASH_RETENTION %q, используем %s", value, DefaultTrashRetention)
+		return DefaultTrashRetention
+	}
+	return retention
+}
+
+// StartTrashPurger запускает фоновое окончательное удаление карточек,
+// пролежавших в корзине дольше срока хранения
+func StartTrashPurger(interval, retention time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			purgeTrash(retention)
+			<-ticker.C
+		}
+	}()
+}
+
+// purgeTrash окончательно удаляет просроченные карточки и их изображения
+func purgeTrash(retention time.Duration) {
+	cards, err := db.GetExpiredDeletedCards(time.Now().Add(-retention))
+	if err != nil {
+		log.Printf("Ошибка получения карточек из корзины: %v", err)
+		return
+	}
+
+	for _, card := range cards {
+		// Собираем изображения галереи и всех версий карточки до удаления истории
+		images, err := db.GetCardImageFiles(card.ID)
+		if err != nil {
+			log.Printf("Ошибка получения изображений карточки %s: %v", card.ID, err)
+			continue
+		}
+
+		if err := db.DeleteCard(card.ID); err != nil {
+			log.Printf("Ошибка удаления карточки %s: %v", card.ID, err)
+			continue
+		}
+
+		// Удаляем изображения карточки и ее версий
+		for _, image := range images {
+			if err := utils.RemoveImage(image); err != nil && !os.IsNotExist(err) {
+				log.Printf("Ошибка при удалении изображения %s: %v", image, err)
+			}
+		}
+	}
+
+	if len(cards) > 0 {
+		log.Printf("Окончательно удалено карточек из корзины: %d", len(cards))
+	}
+}
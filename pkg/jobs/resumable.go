@@ -0,0 +1,72 @@
+package jobs
+
+import (
+	"log"
+	"time"
+
+	"github.com/user/roma/pkg/db"
+	"github.com/user/roma/pkg/utils"
+)
+
+// ResumableUploadCheckInterval — интервал удаления истекших возобновляемых загрузок
+const ResumableUploadCheckInterval = 15 * time.Minute
+
+// StartUploadExpirer запускает фоновое удаление возобновляемых загрузок,
+// которые не завершили или не использовали до истечения срока
+func StartUploadExpirer(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			expireUploads()
+			<-ticker.C
+		}
+	}()
+}
+
+// expireUploads удаляет истекшие загрузки вместе с полученными частями
+func expireUploads() {
+	ids, err := db.GetExpiredUploadIDs(time.Now())
+	if err != nil {
+		log.Printf("Ошибка получения истекших загрузок: %v", err)
+		return
+	}
+
+	removed := 0
+	for _, id := range ids {
+		// Файл удаляем раньше записи: запись без файла удалится при следующей проверке
+		if err := utils.RemovePartialUpload(id); err != nil {
+			log.Printf("Ошибка удаления частей загрузки %s: %v", id, err)
+			continue
+		}
+		if err := db.DeleteUpload(id); err != nil {
+			log.Printf("Ошибка удаления загрузки %s: %v", id, err)
+			continue
+		}
+		removed++
+	}
+
+	// Части загрузок, запись о которых не сохранилась из-за сбоя
+	files, err := utils.ListPartialUploads()
+	if err != nil {
+		log.Printf("Ошибка чтения директории загрузок: %v", err)
+	}
+	for id, modTime := range files {
+		if time.Since(modTime) < utils.UploadExpiration {
+			continue
+		}
+		if exists, err := db.UploadExists(id); err != nil || exists {
+			continue
+		}
+		if err := utils.RemovePartialUpload(id); err != nil {
+			log.Printf("Ошибка удаления частей загрузки %s: %v", id, err)
+			continue
+		}
+		removed++
+	}
+
+	if removed > 0 {
+		log.Printf("Удалено истекших загрузок: %d", removed)
+	}
+}
@@ -0,0 +1,36 @@
+package jobs
+
+import (
+	"log"
+	"time"
+
+	"github.com/user/roma/pkg/db"
+)
+
+// SuspensionCheckInterval определяет, как часто снимаются истекшие блокировки
+const SuspensionCheckInterval = time.Minute
+
+// StartSuspensionExpirer запускает фоновое снятие блокировок, срок которых истек
+func StartSuspensionExpirer(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			liftExpiredSuspensions()
+			<-ticker.C
+		}
+	}()
+}
+
+// liftExpiredSuspensions снимает истекшие блокировки пользователей
+func liftExpiredSuspensions() {
+	lifted, err := db.LiftExpiredSuspensions(time.Now())
+	if err != nil {
+		log.Printf("Ошибка снятия истекших блокировок: %v", err)
+		return
+	}
+	if lifted > 0 {
+		log.Printf("Снято истекших блокировок: %d", lifted)
+	}
+}
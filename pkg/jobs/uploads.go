@@ -0,0 +1,196 @@
+package jobs
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/user/roma/pkg/db"
+	"github.com/user/roma/pkg/storage"
+	"github.com/user/roma/pkg/utils"
+)
+
+// Параметры удаления неиспользуемых файлов по умолчанию
+const (
+	UploadGCInterval     = 6 * time.Hour
+	DefaultUploadGCGrace = 24 * time.Hour
+)
+
+// LegacyTempDir — директория временных файлов загрузки прежних версий сервера.
+// Сервер больше не пишет в нее, поэтому все ее файлы старше срока ожидания лишние.
+const LegacyTempDir = "./temp"
+
+// UploadGCGrace возвращает срок, в течение которого файл без ссылок не удаляется, из переменной
+// окружения UPLOAD_GC_GRACE (например, "24h") или значение по умолчанию. Срок защищает файлы
+// загрузок, которые еще не успели сохраниться в карточке или профиле.
+func UploadGCGrace() time.Duration {
+	value := os.Getenv("UPLOAD_GC_GRACE")
+	if value == "" {
+		return DefaultUploadGCGrace
+	}
+
+	grace, err := time.ParseDuration(value)
+	if err != nil || grace < time.Hour {
+		log.Printf("Неверное значение UPLOAD_GC_GRACE %q (не меньше 1h), используем %s", value, DefaultUploadGCGrace)
+		return DefaultUploadGCGrace
+	}
+	return grace
+}
+
+// UploadGCDryRun возвращает true, если фоновая проверка должна только сообщать
+// о неиспользуемых файлах, не удаляя их (UPLOAD_GC_DRY_RUN=true)
+func UploadGCDryRun() bool {
+	return os.Getenv("UPLOAD_GC_DRY_RUN") == "true"
+}
+
+// UploadGCReport — результат поиска неиспользуемых файлов
+type UploadGCReport struct {
+	Orphans []storage.FileInfo // файлы без ссылок старше срока ожидания; файлы ./temp — с путем
+	Removed int                // удаленные файлы
+	Kept    int                // файлы без ссылок, оставленные до истечения срока ожидания
+	Bytes   int64              // размер найденных файлов
+}
+
+// StartUploadCollector запускает фоновое удаление загруженных файлов, на которые
+// не ссылаются ни карточки, ни профили пользователей
+func StartUploadCollector(interval, grace time.Duration, dryRun bool) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			report, err := CollectOrphanUploads(grace, dryRun)
+			if err != nil {
+				log.Printf("Ошибка поиска неиспользуемых файлов: %v", err)
+			} else if len(report.Orphans) > 0 {
+				if dryRun {
+					for _, file := range report.Orphans {
+						log.Printf("Неиспользуемый файл %s (%d байт, изменен %s)", file.Name, file.Size, file.ModTime.Format(time.RFC3339))
+					}
+				}
+				log.Printf("Неиспользуемых файлов: %d (%d байт), удалено: %d", len(report.Orphans), report.Bytes, report.Removed)
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// CollectOrphanUploads находит файлы хранилища и директории ./temp, на которые не ссылаются
+// профили пользователей, карточки, их галереи и версии, и удаляет те, что старше grace.
+// Файлы одного изображения (оригинал, уменьшенные копии и копии в WebP) удаляются вместе.
+// В режиме dryRun файлы только перечисляются.
+func CollectOrphanUploads(grace time.Duration, dryRun bool) (UploadGCReport, error) {
+	report := UploadGCReport{}
+	before := time.Now().Add(-grace)
+
+	// Файлы перечисляем до чтения ссылок: файл, загруженный после чтения ссылок,
+	// не старше срока ожидания и не будет удален
+	files, err := utils.Store.List()
+	if err != nil {
+		return report, fmt.Errorf("ошибка получения списка файлов: %w", err)
+	}
+	referencedFiles, err := db.GetReferencedImageFiles()
+	if err != nil {
+		return report, fmt.Errorf("ошибка получения ссылок на файлы: %w", err)
+	}
+
+	// Пустая база при непустом хранилище похожа на ошибку настройки, а не на ненужные файлы
+	if len(referencedFiles) == 0 && len(files) > 0 {
+		return report, fmt.Errorf("в базе нет ссылок ни на один из файлов хранилища (%d), удаление пропущено", len(files))
+	}
+
+	referenced := map[string]bool{}
+	for name := range referencedFiles {
+		if stem, ok := utils.ImageStem(name); ok {
+			referenced[stem] = true
+		}
+	}
+
+	// Группируем файлы без ссылок по изображению, к которому они относятся
+	groups := map[string][]storage.FileInfo{}
+	for _, file := range files {
+		stem, ok := utils.ImageStem(file.Name)
+		if !ok || referenced[stem] {
+			continue // посторонние файлы хранилища не трогаем
+		}
+		groups[stem] = append(groups[stem], file)
+	}
+
+	for stem, group := range groups {
+		names := make([]string, 0, len(group))
+		recent := false
+		for _, file := range group {
+			names = append(names, file.Name)
+			if !file.ModTime.Before(before) {
+				recent = true
+			}
+		}
+		if recent {
+			report.Kept += len(group)
+			continue
+		}
+
+		if !dryRun {
+			removed, err := utils.RemoveOrphanedImage(stem, names, before)
+			if err != nil {
+				log.Printf("Ошибка удаления неиспользуемого изображения %s: %v", stem, err)
+				continue
+			}
+			if !removed {
+				report.Kept += len(group)
+				continue
+			}
+			report.Removed += len(group)
+		}
+
+		for _, file := range group {
+			report.Orphans = append(report.Orphans, file)
+			report.Bytes += file.Size
+		}
+	}
+
+	if err := collectLegacyTemp(before, dryRun, &report); err != nil {
+		return report, err
+	}
+
+	return report, nil
+}
+
+// collectLegacyTemp удаляет файлы, оставшиеся в ./temp от прерванных загрузок прежних версий
+func collectLegacyTemp(before time.Time, dryRun bool, report *UploadGCReport) error {
+	entries, err := os.ReadDir(LegacyTempDir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("ошибка чтения директории %s: %w", LegacyTempDir, err)
+	}
+
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue // файл удален во время обхода
+		}
+		if !info.ModTime().Before(before) {
+			report.Kept++
+			continue
+		}
+
+		path := filepath.Join(LegacyTempDir, entry.Name())
+		if !dryRun {
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				log.Printf("Ошибка удаления временного файла %s: %v", path, err)
+				continue
+			}
+			report.Removed++
+		}
+		report.Orphans = append(report.Orphans, storage.FileInfo{Name: path, Size: info.Size(), ModTime: info.ModTime()})
+		report.Bytes += info.Size()
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package jobs
+
+import (
+	"log"
+	"os"
+	"time"
+
+	"github.com/user/roma/pkg/db"
+	"github.com/user/roma/pkg/models"
+)
+
+// Параметры учета просмотров по умолчанию
+const (
+	ViewFlushInterval      = 10 * time.Second
+	ViewPruneInterval      = time.Hour
+	DefaultViewDedupWindow = 30 * time.Minute
+	viewQueueSize          = 4096
+	maxViewBatchSize       = 500
+)
+
+// viewQueue принимает просмотры от обработчиков запросов, чтобы запись
+// в статистику не замедляла чтение карточек
+var viewQueue = make(chan models.CardView, viewQueueSize)
+
+// ViewDedupWindow возвращает окно дедупликации просмотров из переменной
+// окружения CARD_VIEW_DEDUP_WINDOW (например, "30m") или значение по умолчанию
+func ViewDedupWindow() time.Duration {
+	value := os.Getenv("CARD_VIEW_DEDUP_WINDOW")
+	if value == "" {
+		return DefaultViewDedupWindow
+	}
+
+	window, err := time.ParseDuration(value)
+	if err != nil || window < 0 {
+		log.Printf("Неверное значение CARD_VIEW_DEDUP_WINDOW %q, используем %s", value, DefaultViewDedupWindow)
+		return DefaultViewDedupWindow
+	}
+	return window
+}
+
+// TrackCardView ставит просмотр карточки в очередь на запись.
+// Если очередь переполнена, просмотр отбрасывается, чтобы не блокировать запрос.
+func TrackCardView(view models.CardView) {
+	select {
+	case viewQueue <- view:
+	default:
+		log.Printf("Очередь просмотров переполнена, просмотр карточки %s не учтен", view.CardID)
+	}
+}
+
+// StartViewRecorder запускает фоновую запись накопленных просмотров в дневную статистику
+// и раз в ViewPruneInterval удаляет записи о зрителях, не нужные для дедупликации
+func StartViewRecorder(interval, window time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		pruneTicker := time.NewTicker(ViewPruneInterval)
+		defer pruneTicker.Stop()
+
+		batch := []models.CardView{}
+		for {
+			select {
+			case view := <-viewQueue:
+				batch = append(batch, view)
+				if len(batch) < maxViewBatchSize {
+					continue
+				}
+			case <-ticker.C:
+				if len(batch) == 0 {
+					continue
+				}
+			case <-pruneTicker.C:
+				pruneViewers(window)
+				continue
+			}
+
+			recordViews(batch, window)
+			batch = batch[:0]
+		}
+	}()
+}
+
+// recordViews сохраняет пачку просмотров
+func recordViews(views []models.CardView, window time.Duration) {
+	if err := db.RecordCardViews(views, window); err != nil {
+		log.Printf("Ошибка записи просмотров карточек (%d): %v", len(views), err)
+	}
+}
+
+// pruneViewers удаляет зрителей, последний просмотр которых старше окна дедупликации,
+// и дневных зрителей за прошедшие дни. Вчерашние записи остаются: в очереди еще могут
+// быть просмотры, сделанные до полуночи.
+func pruneViewers(window time.Duration) {
+	now := time.Now()
+	removed, err := db.PruneCardViewers(now.Add(-window), now.AddDate(0, 0, -1))
+	if err != nil {
+		log.Printf("Ошибка удаления устаревших записей о зрителях: %v", err)
+		return
+	}
+	if removed > 0 {
+		log.Printf("Удалено устаревших записей о зрителях: %d", removed)
+	}
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/user/roma/pkg/db"
+	"github.com/user/roma/pkg/models"
 	"github.com/user/roma/pkg/utils"
 )
 
@@ -48,6 +49,11 @@ func Auth() fiber.Handler {
 			})
 		}
 
+		// Заблокированные пользователи не могут пользоваться своими токенами
+		if user.IsSuspended() {
+			return c.Status(fiber.StatusForbidden).JSON(SuspendedResponse(user))
+		}
+
 		// Сохраняем пользователя в локальном хранилище для использования в обработчиках
 		c.Locals("userID", user.ID)
 		c.Locals("user", user)
@@ -55,3 +61,59 @@ func Auth() fiber.Handler {
 		return c.Next()
 	}
 }
+
+// OptionalAuth middleware определяет пользователя по JWT токену, если он передан,
+// но не отклоняет запросы без токена или с неверным токеном
+func OptionalAuth() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		// Проверяем формат заголовка Authorization
+		parts := strings.Split(c.Get("Authorization"), " ")
+		if len(parts) != 2 || parts[0] != "Bearer" {
+			return c.Next()
+		}
+
+		// Проверяем токен
+		userID, err := utils.VerifyJWT(parts[1])
+		if err != nil {
+			return c.Next()
+		}
+
+		// Получаем пользователя из базы данных (заблокированные считаются анонимными)
+		user, err := db.GetUserByID(userID)
+		if err != nil || user.IsSuspended() {
+			return c.Next()
+		}
+
+		// Сохраняем пользователя в локальном хранилище для использования в обработчиках
+		c.Locals("userID", user.ID)
+		c.Locals("user", user)
+
+		return c.Next()
+	}
+}
+
+// Admin middleware пропускает только администраторов. Используется после Auth.
+func Admin() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		user, ok := c.Locals("user").(*models.User)
+		if !ok || !user.IsAdmin() {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "требуются права администратора",
+			})
+		}
+
+		return c.Next()
+	}
+}
+
+// SuspendedResponse формирует ответ об ошибке для заблокированного пользователя
+func SuspendedResponse(user *models.User) fiber.Map {
+	response := fiber.Map{
+		"error":  "аккаунт заблокирован",
+		"reason": user.SuspendReason,
+	}
+	if user.SuspendedUntil != nil {
+		response["until"] = user.SuspendedUntil
+	}
+	return response
+}
@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"io"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// tusContentType — тип тела части возобновляемой загрузки
+const tusContentType = "application/offset+octet-stream"
+
+// LimitBody ограничивает размер тела запроса. Сервер читает тела потоком (StreamRequestBody),
+// чтобы части возобновляемых загрузок записывались по мере получения, и при этом не отклоняет
+// тела больше BodyLimit, поэтому остальные запросы проверяются и читаются целиком здесь.
+// Проверенное тело отмечается в c.Locals("bodyLimited"), и только такое тело можно читать
+// до обработчика.
+func LimitBody(limit int) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		// Размер части загрузки проверяет обработчик: она не может выйти за размер файла.
+		// Части с другим типом тела обработчик отклоняет, поэтому они ограничиваются как обычно.
+		if c.Method() == fiber.MethodPatch && strings.HasPrefix(c.Path(), "/api/uploads/") &&
+			c.Get(fiber.HeaderContentType) == tusContentType {
+			return c.Next()
+		}
+
+		length := c.Request().Header.ContentLength()
+		if length > limit {
+			return bodyTooLarge(c)
+		}
+
+		// Размер тела без Content-Length (chunked) узнаем, только прочитав его
+		if length == -1 {
+			if stream := c.Context().RequestBodyStream(); stream != nil {
+				body, err := io.ReadAll(io.LimitReader(stream, int64(limit)+1))
+				if err != nil {
+					c.Context().SetConnectionClose()
+					return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+						"error": "ошибка чтения тела запроса",
+					})
+				}
+				if len(body) > limit {
+					return bodyTooLarge(c)
+				}
+				c.Request().SetBody(body)
+			}
+		}
+
+		c.Locals("bodyLimited", true)
+		return c.Next()
+	}
+}
+
+// bodyTooLarge отклоняет запрос, не читая тело. Непрочитанное тело осталось бы
+// в соединении, поэтому оно закрывается после ответа.
+func bodyTooLarge(c *fiber.Ctx) error {
+	c.Context().SetConnectionClose()
+	return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{
+		"error": "слишком большой запрос",
+	})
+}
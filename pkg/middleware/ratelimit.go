@@ -0,0 +1,186 @@
+package middleware
+
+import (
+	"encoding/json"
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/user/roma/pkg/ratelimit"
+	"github.com/user/roma/pkg/utils"
+)
+
+// RateLimitPolicies содержит политики ограничения частоты запросов для групп маршрутов
+type RateLimitPolicies struct {
+	Auth    ratelimit.Policy // регистрация и вход
+	Writes  ratelimit.Policy // изменяющие запросы
+	Uploads ratelimit.Policy // запросы с загрузкой файлов
+	Reads   ratelimit.Policy // чтение
+}
+
+// Политики по умолчанию
+var defaultRateLimits = map[string]string{
+	"auth":    "10/1m",
+	"writes":  "60/1m",
+	"uploads": "20/1m",
+	"reads":   "300/1m",
+}
+
+// LoadRateLimitPolicies читает политики из переменных окружения RATE_LIMIT_AUTH,
+// RATE_LIMIT_WRITES, RATE_LIMIT_UPLOADS и RATE_LIMIT_READS в формате "лимит/период"
+// (например, "60/1m"; "off" отключает ограничение) или использует значения по умолчанию
+func LoadRateLimitPolicies() RateLimitPolicies {
+	return RateLimitPolicies{
+		Auth:    rateLimitPolicy("auth"),
+		Writes:  rateLimitPolicy("writes"),
+		Uploads: rateLimitPolicy("uploads"),
+		Reads:   rateLimitPolicy("reads"),
+	}
+}
+
+// rateLimitPolicy читает политику группы из переменной окружения
+func rateLimitPolicy(name string) ratelimit.Policy {
+	variable := "RATE_LIMIT_" + strings.ToUpper(name)
+	value := os.Getenv(variable)
+	if value != "" {
+		policy, err := ratelimit.ParsePolicy(name, value)
+		if err == nil {
+			return policy
+		}
+		log.Printf("Неверное значение %s %q (%v), используем %s", variable, value, err, defaultRateLimits[name])
+	}
+
+	policy, _ := ratelimit.ParsePolicy(name, defaultRateLimits[name])
+	return policy
+}
+
+// RateLimit middleware ограничивает частоту запросов по политике. Запросы с действительным
+// токеном учитываются по пользователю, остальные — по IP-адресу.
+func RateLimit(store ratelimit.Store, policy ratelimit.Policy) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		return limitRequest(c, store, policy)
+	}
+}
+
+// RateLimitRequests middleware выбирает политику по запросу: чтение, загрузка файлов
+// или остальные изменяющие запросы
+func RateLimitRequests(store ratelimit.Store, policies RateLimitPolicies) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		policy := policies.Writes
+		switch {
+		case c.Method() == fiber.MethodGet || c.Method() == fiber.MethodHead || c.Method() == fiber.MethodOptions:
+			policy = policies.Reads
+		case hasUploadedFiles(c):
+			policy = policies.Uploads
+		}
+		return limitRequest(c, store, policy)
+	}
+}
+
+// limitRequest забирает токен для запроса и отклоняет его, если токенов не осталось
+func limitRequest(c *fiber.Ctx, store ratelimit.Store, policy ratelimit.Policy) error {
+	if !policy.Enabled() {
+		return c.Next()
+	}
+
+	decision, err := store.Take(policy.Name+":"+rateLimitKey(c), policy, time.Now())
+	if err != nil {
+		// Недоступность хранилища не должна останавливать сервис
+		log.Printf("Ошибка ограничения частоты запросов: %v", err)
+		return c.Next()
+	}
+
+	c.Set("X-RateLimit-Limit", strconv.Itoa(decision.Limit))
+	c.Set("X-RateLimit-Remaining", strconv.Itoa(decision.Remaining))
+	c.Set("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(decision.ResetAfter)))
+
+	if !decision.Allowed {
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(ceilSeconds(decision.RetryAfter)))
+		return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+			"error": "слишком много запросов, попробуйте позже",
+		})
+	}
+
+	return c.Next()
+}
+
+// rateLimitKey определяет, чьи запросы учитываются: пользователя с действительным токеном
+// или IP-адреса. Токен проверяется без обращения к базе данных.
+func rateLimitKey(c *fiber.Ctx) string {
+	parts := strings.Split(c.Get("Authorization"), " ")
+	if len(parts) == 2 && parts[0] == "Bearer" {
+		if userID, err := utils.VerifyJWT(parts[1]); err == nil {
+			return "user:" + userID
+		}
+	}
+	return "ip:" + c.IP()
+}
+
+// imageFields — поля формы и JSON, в которых передаются изображения: строки base64
+// или ID возобновляемых загрузок
+var imageFields = []string{"image", "images", "image_upload_id", "images_upload_id"}
+
+// hasUploadedFiles проверяет, загружает ли запрос изображение: файлом multipart-формы,
+// строкой base64 или ID загрузки в поле формы или JSON, телом с типом image/*
+// или частью файла возобновляемой загрузки. Тело читается, только если его размер
+// уже ограничил LimitBody.
+func hasUploadedFiles(c *fiber.Ctx) bool {
+	contentType := c.Get(fiber.HeaderContentType)
+	if contentType == tusContentType || strings.HasPrefix(contentType, "image/") {
+		return true
+	}
+	if limited, _ := c.Locals("bodyLimited").(bool); !limited {
+		return false
+	}
+
+	switch {
+	case strings.HasPrefix(contentType, fiber.MIMEMultipartForm):
+		form, err := c.MultipartForm()
+		if err != nil {
+			return false
+		}
+		if len(form.File) > 0 {
+			return true
+		}
+		for _, field := range imageFields {
+			for _, value := range form.Value[field] {
+				if value != "" {
+					return true
+				}
+			}
+		}
+
+	case strings.HasPrefix(contentType, fiber.MIMEApplicationForm):
+		args := c.Request().PostArgs()
+		for _, field := range imageFields {
+			for _, value := range args.PeekMulti(field) {
+				if len(value) > 0 {
+					return true
+				}
+			}
+		}
+
+	case strings.HasPrefix(contentType, fiber.MIMEApplicationJSON):
+		var body map[string]json.RawMessage
+		if json.Unmarshal(c.Body(), &body) != nil {
+			return false
+		}
+		for _, field := range imageFields {
+			switch string(body[field]) {
+			case "", "null", `""`, "[]":
+			default:
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ceilSeconds округляет длительность вверх до целых секунд
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
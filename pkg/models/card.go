@@ -4,49 +4,122 @@ import (
 	"time"
 )
 
+// Статусы публикации карточки
+const (
+	CardStatusDraft     = "draft"
+	CardStatusScheduled = "scheduled"
+	CardStatusPublished = "published"
+	CardStatusArchived  = "archived"
+)
+
+// Уровни видимости карточки
+const (
+	CardVisibilityPublic    = "public"    // видна всем и попадает в ленты
+	CardVisibilityUnlisted  = "unlisted"  // доступна только по прямой ссылке
+	CardVisibilityFollowers = "followers" // видна только подписчикам автора
+	CardVisibilityPrivate   = "private"   // видна только автору
+)
+
+// MaxPinnedCards — максимальное количество карточек, закрепленных в профиле
+const MaxPinnedCards = 3
+
 // Card представляет карточку пользователя
 type Card struct {
-	ID          string    `json:"id"`
-	UserID      string    `json:"user_id"`
-	UserName    string    `json:"user_name"`
-	Image       string    `json:"image"`
-	Title       string    `json:"title"`
-	Description string    `json:"description"`
-	Text        string    `json:"text"`
-	Likes       int       `json:"likes"`
-	LikedBy     []string  `json:"-"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID             string            `json:"id"`
+	UserID         string            `json:"user_id"`
+	UserName       string            `json:"user_name"`
+	Image          string            `json:"image"`
+	Title          string            `json:"title"`
+	Description    string            `json:"description"`
+	Text           string            `json:"text"`
+	Likes          int               `json:"likes"`
+	LikedBy        []string          `json:"-"`
+	Images         []CardImage       `json:"images"`
+	MentionedUsers map[string]string `json:"-"` // логин в нижнем регистре -> ID пользователя
+	Reposts        int               `json:"reposts"`
+	QuoteCardID    string            `json:"quote_card_id,omitempty"` // цитируемая карточка
+	PinPosition    *int              `json:"-"`                       // позиция среди закрепленных, nil если не закреплена
+	Visibility     string            `json:"visibility"`
+	Status         string            `json:"status"`
+	PublishAt      *time.Time        `json:"publish_at,omitempty"`
+	DeletedAt      *time.Time        `json:"deleted_at,omitempty"`
+	HiddenAt       *time.Time        `json:"hidden_at,omitempty"` // скрыта модератором
+	CreatedAt      time.Time         `json:"created_at"`
+	UpdatedAt      time.Time         `json:"updated_at"`
+}
+
+// IsPublished сообщает, опубликована ли карточка
+func (c *Card) IsPublished() bool {
+	return c.Status == CardStatusPublished
 }
 
 // CardCreate представляет данные для создания карточки
 type CardCreate struct {
-	Image       string `json:"image"`
-	Title       string `json:"title"`
-	Description string `json:"description"`
-	Text        string `json:"text"`
+	Image       string      `json:"image"`
+	Title       string      `json:"title"`
+	Description string      `json:"description"`
+	Text        string      `json:"text"`
+	Visibility  string      `json:"visibility"`
+	Status      string      `json:"status"`
+	PublishAt   *time.Time  `json:"publish_at"`
+	QuoteCardID string      `json:"quote_card_id"`
+	Images      []CardImage `json:"-"` // галерея, первое изображение становится обложкой
 }
 
-// CardUpdate представляет данные для обновления карточки
+// CardUpdate представляет данные для обновления карточки.
+// Обложка карточки не задается напрямую, а вычисляется по галерее.
 type CardUpdate struct {
-	Image       string `json:"image"`
-	Title       string `json:"title"`
-	Description string `json:"description"`
-	Text        string `json:"text"`
+	Title        string      `json:"title"`
+	Description  string      `json:"description"`
+	Text         string      `json:"text"`
+	Visibility   string      `json:"visibility"`
+	Status       string      `json:"status"`
+	PublishAt    *time.Time  `json:"publish_at"`
+	CoverImage   *CardImage  `json:"-"` // заменяет текущую обложку
+	NewImages    []CardImage `json:"-"` // добавляются в конец галереи
+	RemoveImages bool        `json:"-"` // очищает галерею перед добавлением
 }
 
 // CardResponse представляет карточку для ответа
 type CardResponse struct {
-	ID          string    `json:"id"`
-	UserID      string    `json:"user_id"`
-	UserName    string    `json:"user_name"`
-	Image       string    `json:"image"`
-	Title       string    `json:"title"`
-	Description string    `json:"description"`
-	Text        string    `json:"text"`
-	Likes       int       `json:"likes"`
-	IsLiked     bool      `json:"is_liked"`
-	CreatedAt   time.Time `json:"created_at"`
+	ID          string              `json:"id"`
+	UserID      string              `json:"user_id"`
+	UserName    string              `json:"user_name"`
+	Image       string              `json:"image"`
+	ImageSrcSet map[string]string   `json:"image_srcset,omitempty"` // уменьшенные копии обложки
+	Images      []CardImageResponse `json:"images"`
+	Title       string              `json:"title"`
+	Description string              `json:"description"`
+	Text        string              `json:"text"`
+	TextHTML    string              `json:"text_html"`
+	Entities    CardEntities        `json:"entities"`
+	Likes       int                 `json:"likes"`
+	IsLiked     bool                `json:"is_liked"`
+	Reposts     int                 `json:"reposts"`
+	IsPinned    bool                `json:"is_pinned"`
+	Quote       *QuotedCard         `json:"quote,omitempty"`
+	RepostedBy  *RepostInfo         `json:"reposted_by,omitempty"` // заполняется в ленте репостнувшего
+	Visibility  string              `json:"visibility"`
+	Status      string              `json:"status"`
+	PublishAt   *time.Time          `json:"publish_at,omitempty"`
+	DeletedAt   *time.Time          `json:"deleted_at,omitempty"`
+	HiddenAt    *time.Time          `json:"hidden_at,omitempty"`
+	CreatedAt   time.Time           `json:"created_at"`
+}
+
+// PinnedCardsOrder представляет новый порядок закрепленных карточек
+type PinnedCardsOrder struct {
+	CardIDs []string `json:"card_ids"`
+}
+
+// CardPreview представляет текст карточки для предпросмотра
+type CardPreview struct {
+	Text string `json:"text" form:"text"`
+}
+
+// CardPreviewResponse представляет результат предпросмотра текста карточки
+type CardPreviewResponse struct {
+	TextHTML string `json:"text_html"`
 }
 
 // PaginationResponse представляет ответ с пагинацией
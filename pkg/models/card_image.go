@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+)
+
+// CardImage представляет изображение из галереи карточки
+type CardImage struct {
+	ID        string    `json:"id"`
+	CardID    string    `json:"card_id"`
+	Filename  string    `json:"filename"`
+	Position  int       `json:"position"`
+	Caption   string    `json:"caption"`
+	Alt       string    `json:"alt"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CardImageResponse представляет изображение галереи для ответа
+type CardImageResponse struct {
+	ID       string            `json:"id"`
+	URL      string            `json:"url"`
+	SrcSet   map[string]string `json:"srcset,omitempty"` // уменьшенные копии по ширине ("640w")
+	Position int               `json:"position"`
+	Caption  string            `json:"caption"`
+	Alt      string            `json:"alt"`
+}
+
+// CardImagesOrder представляет новый порядок изображений галереи
+type CardImagesOrder struct {
+	ImageIDs []string `json:"image_ids"`
+}
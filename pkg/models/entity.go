@@ -0,0 +1,24 @@
+package models
+
+// Типы сущностей в тексте карточки
+const (
+	EntityMention = "mention"
+	EntityHashtag = "hashtag"
+)
+
+// TextEntity представляет упоминание или хештег в тексте.
+// Start и End — смещения в символах Unicode (code points), End не включается.
+type TextEntity struct {
+	Type   string `json:"type"`
+	Value  string `json:"value"` // логин без @ или тег без #
+	Start  int    `json:"start"`
+	End    int    `json:"end"`
+	UserID string `json:"user_id,omitempty"` // ID упомянутого пользователя
+}
+
+// CardEntities представляет сущности в полях карточки
+type CardEntities struct {
+	Title       []TextEntity `json:"title"`
+	Description []TextEntity `json:"description"`
+	Text        []TextEntity `json:"text"`
+}
@@ -0,0 +1,110 @@
+package models
+
+import (
+	"time"
+)
+
+// Причины жалоб на карточки
+const (
+	ReportReasonSpam           = "spam"
+	ReportReasonHarassment     = "harassment"
+	ReportReasonHate           = "hate"
+	ReportReasonViolence       = "violence"
+	ReportReasonNudity         = "nudity"
+	ReportReasonMisinformation = "misinformation"
+	ReportReasonCopyright      = "copyright"
+	ReportReasonOther          = "other"     // требует комментария
+	ReportReasonAutomatic      = "automatic" // карточка задержана проверкой содержимого
+)
+
+// SystemReporterID указывается автором жалоб, созданных проверкой содержимого
+const SystemReporterID = "system"
+
+// Статусы жалоб
+const (
+	ReportStatusOpen     = "open"     // ожидает модератора
+	ReportStatusClaimed  = "claimed"  // взята модератором в работу
+	ReportStatusResolved = "resolved" // решение принято
+)
+
+// Решения модератора по жалобе
+const (
+	ModerationActionDismiss     = "dismiss"      // жалоба отклонена
+	ModerationActionHideCard    = "hide_card"    // карточка скрыта от всех, кроме автора
+	ModerationActionDeleteCard  = "delete_card"  // карточка удалена окончательно
+	ModerationActionSuspendUser = "suspend_user" // автор карточки заблокирован
+	ModerationActionApproveCard = "approve_card" // скрытая карточка снова показана всем
+	ModerationActionClaim       = "claim"        // жалоба взята в работу (только в журнале)
+)
+
+// Действия администратора с пользователями (записываются в журнал модерации)
+const (
+	ModerationActionUnsuspendUser   = "unsuspend_user"
+	ModerationActionShadowBanUser   = "shadow_ban_user"
+	ModerationActionUnshadowBanUser = "unshadow_ban_user"
+)
+
+// Report представляет жалобу на карточку
+type Report struct {
+	ID             string     `json:"id"`
+	CardID         string     `json:"card_id"`
+	CardTitle      string     `json:"card_title,omitempty"`   // пусто, если карточка уже удалена
+	CardUserID     string     `json:"card_user_id,omitempty"` // автор карточки
+	ReporterID     string     `json:"reporter_id"`
+	Reason         string     `json:"reason"`
+	Comment        string     `json:"comment,omitempty"`
+	ContentCheck   string     `json:"content_check,omitempty"` // правила проверки содержимого, сработавшие на комментарии
+	Status         string     `json:"status"`
+	ClaimedBy      string     `json:"claimed_by,omitempty"`
+	ClaimedAt      *time.Time `json:"claimed_at,omitempty"`
+	Action         string     `json:"action,omitempty"`
+	ResolutionNote string     `json:"resolution_note,omitempty"`
+	ResolvedBy     string     `json:"resolved_by,omitempty"`
+	ResolvedAt     *time.Time `json:"resolved_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// ReportCreate представляет данные для создания жалобы
+type ReportCreate struct {
+	Reason  string `json:"reason"`
+	Comment string `json:"comment"`
+}
+
+// ReportResolve представляет решение модератора по жалобе
+type ReportResolve struct {
+	Action string `json:"action"`
+	Note   string `json:"note"`
+}
+
+// ReportsPage представляет страницу очереди модерации
+type ReportsPage struct {
+	Reports      []Report `json:"reports"`
+	Page         int      `json:"page"`
+	TotalPages   int      `json:"total_pages"`
+	TotalReports int      `json:"total_reports"`
+}
+
+// ModerationLogEntry представляет запись журнала решений модераторов
+type ModerationLogEntry struct {
+	ID          string    `json:"id"`
+	ModeratorID string    `json:"moderator_id"`
+	ReportID    string    `json:"report_id,omitempty"`
+	Action      string    `json:"action"`
+	TargetType  string    `json:"target_type"` // card или user
+	TargetID    string    `json:"target_id"`
+	Note        string    `json:"note,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// ModerationLogPage представляет страницу журнала модерации
+type ModerationLogPage struct {
+	Entries      []ModerationLogEntry `json:"entries"`
+	Page         int                  `json:"page"`
+	TotalPages   int                  `json:"total_pages"`
+	TotalEntries int                  `json:"total_entries"`
+}
+
+// ModerationNote представляет комментарий администратора к действию
+type ModerationNote struct {
+	Note string `json:"note"`
+}
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+)
+
+// RepostInfo описывает репост карточки в ленте пользователя
+type RepostInfo struct {
+	UserID     string    `json:"user_id"`
+	UserName   string    `json:"user_name"`
+	RepostedAt time.Time `json:"reposted_at"`
+}
+
+// QuotedCard представляет цитируемую карточку внутри карточки-цитаты.
+// Если оригинал удален или недоступен пользователю, возвращается только ID с Available = false.
+type QuotedCard struct {
+	ID          string     `json:"id"`
+	Available   bool       `json:"available"`
+	UserID      string     `json:"user_id,omitempty"`
+	UserName    string     `json:"user_name,omitempty"`
+	Image       string     `json:"image,omitempty"`
+	Title       string     `json:"title,omitempty"`
+	Description string     `json:"description,omitempty"`
+	CreatedAt   *time.Time `json:"created_at,omitempty"`
+}
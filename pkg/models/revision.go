@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+)
+
+// CardRevision представляет сохраненную версию карточки
+type CardRevision struct {
+	ID          string    `json:"id"`
+	CardID      string    `json:"card_id"`
+	Revision    int       `json:"revision"`
+	Image       string    `json:"image"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	Text        string    `json:"text"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// CardRevisionResponse представляет версию карточки для ответа
+type CardRevisionResponse struct {
+	Revision    int       `json:"revision"`
+	Image       string    `json:"image"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	Text        string    `json:"text"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// DiffLine представляет строку построчного сравнения текста
+type DiffLine struct {
+	Op   string `json:"op"` // equal, insert или delete
+	Text string `json:"text"`
+}
+
+// RevisionDiffResponse представляет разницу текста между двумя версиями карточки
+type RevisionDiffResponse struct {
+	From  int        `json:"from"`
+	To    int        `json:"to"`
+	Lines []DiffLine `json:"lines"`
+}
@@ -0,0 +1,56 @@
+package models
+
+import (
+	"time"
+)
+
+// CardView представляет просмотр карточки.
+// ViewerKey — ID авторизованного пользователя или хеш IP-адреса анонимного зрителя.
+type CardView struct {
+	CardID    string
+	ViewerKey string
+	ViewedAt  time.Time
+}
+
+// CardStatsDay представляет статистику карточки за один день (UTC)
+type CardStatsDay struct {
+	Date          string `json:"date"`
+	Views         int    `json:"views"`
+	UniqueViewers int    `json:"unique_viewers"`
+	Likes         int    `json:"likes"`
+}
+
+// CardStatsResponse представляет статистику карточки для автора
+type CardStatsResponse struct {
+	CardID        string         `json:"card_id"`
+	Views         int            `json:"views"`
+	UniqueViewers int            `json:"unique_viewers"`
+	Likes         int            `json:"likes"`
+	Days          []CardStatsDay `json:"days"`
+}
+
+// ProfileStatsDay представляет статистику автора за один день (UTC)
+type ProfileStatsDay struct {
+	Date         string `json:"date"`
+	Likes        int    `json:"likes"`
+	NewFollowers int    `json:"new_followers"`
+	Followers    int    `json:"followers"` // количество подписчиков на конец дня
+}
+
+// TopCard представляет карточку в рейтинге самых популярных карточек автора
+type TopCard struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+	Likes int    `json:"likes"`
+}
+
+// ProfileStatsResponse представляет сводную статистику автора
+type ProfileStatsResponse struct {
+	TotalCards  int               `json:"total_cards"`
+	TotalLikes  int               `json:"total_likes"`
+	TotalViews  int               `json:"total_views"`
+	Followers   int               `json:"followers"`
+	TopCards    []TopCard         `json:"top_cards"`
+	Days        []ProfileStatsDay `json:"days"`
+	GeneratedAt time.Time         `json:"generated_at"`
+}
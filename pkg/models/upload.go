@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+)
+
+// Upload — возобновляемая загрузка файла по частям. Завершенную загрузку можно указать
+// вместо файла при создании карточки или смене изображения профиля.
+type Upload struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"-"`
+	Length    int64     `json:"length"`   // полный размер файла
+	Offset    int64     `json:"offset"`   // сколько байт уже получено
+	Metadata  string    `json:"-"`        // заголовок Upload-Metadata протокола tus
+	Complete  bool      `json:"complete"` // файл получен полностью
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"` // после этого времени загрузка удаляется
+}
@@ -4,17 +4,52 @@ import (
 	"time"
 )
 
+// Роли пользователей
+const (
+	UserRoleUser  = "user"
+	UserRoleAdmin = "admin"
+)
+
 // User represents user model
 type User struct {
-	ID            string    `json:"id"`
-	Login         string    `json:"login"`
-	Email         string    `json:"email"`
-	Password      string    `json:"-"`
-	ProfileImage  string    `json:"profile_image,omitempty"`
-	ProfileBanner string    `json:"profile_banner,omitempty"`
-	Description   string    `json:"description,omitempty"`
-	CreatedAt     time.Time `json:"created_at"`
-	UpdatedAt     time.Time `json:"updated_at"`
+	ID             string     `json:"id"`
+	Login          string     `json:"login"`
+	Email          string     `json:"email"`
+	Password       string     `json:"-"`
+	ProfileImage   string     `json:"profile_image,omitempty"`
+	ProfileBanner  string     `json:"profile_banner,omitempty"`
+	Description    string     `json:"description,omitempty"`
+	Role           string     `json:"role"`
+	SuspendedAt    *time.Time `json:"suspended_at,omitempty"`
+	SuspendedUntil *time.Time `json:"suspended_until,omitempty"` // nil — бессрочная блокировка
+	SuspendReason  string     `json:"suspend_reason,omitempty"`
+	ShadowBannedAt *time.Time `json:"-"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// IsAdmin сообщает, является ли пользователь администратором
+func (u *User) IsAdmin() bool {
+	return u.Role == UserRoleAdmin
+}
+
+// IsSuspended сообщает, заблокирован ли пользователь в данный момент
+func (u *User) IsSuspended() bool {
+	if u.SuspendedAt == nil {
+		return false
+	}
+	return u.SuspendedUntil == nil || time.Now().Before(*u.SuspendedUntil)
+}
+
+// IsShadowBanned сообщает, скрыт ли контент пользователя от остальных
+func (u *User) IsShadowBanned() bool {
+	return u.ShadowBannedAt != nil
+}
+
+// UserSuspend представляет данные для блокировки пользователя
+type UserSuspend struct {
+	Reason string     `json:"reason"`
+	Until  *time.Time `json:"until"` // если не указано, блокировка бессрочная
 }
 
 // UserLogin представляет данные для авторизации
@@ -33,13 +68,16 @@ type UserRegister struct {
 
 // UserResponse представляет данные пользователя для ответа
 type UserResponse struct {
-	ID            string    `json:"id"`
-	Login         string    `json:"login"`
-	Email         string    `json:"email"`
-	ProfileImage  string    `json:"profile_image,omitempty"`
-	ProfileBanner string    `json:"profile_banner,omitempty"`
-	Description   string    `json:"description,omitempty"`
-	CreatedAt     time.Time `json:"created_at"`
+	ID                  string            `json:"id"`
+	Login               string            `json:"login"`
+	Email               string            `json:"email"`
+	ProfileImage        string            `json:"profile_image,omitempty"`
+	ProfileImageSrcSet  map[string]string `json:"profile_image_srcset,omitempty"` // уменьшенные копии по ширине ("150w")
+	ProfileBanner       string            `json:"profile_banner,omitempty"`
+	ProfileBannerSrcSet map[string]string `json:"profile_banner_srcset,omitempty"`
+	Description         string            `json:"description,omitempty"`
+	Role                string            `json:"role"`
+	CreatedAt           time.Time         `json:"created_at"`
 }
 
 // TokenResponse представляет ответ с токеном авторизации
@@ -0,0 +1,143 @@
+// Package ratelimit ограничивает частоту запросов по алгоритму token bucket:
+// у каждого ключа есть корзина из Limit токенов, которая равномерно пополняется
+// за Period, а каждый запрос забирает один токен.
+package ratelimit
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Policy описывает ограничение: не более Limit запросов подряд с пополнением Limit токенов за Period.
+// Политика с нулевым Limit ничего не ограничивает.
+type Policy struct {
+	Name   string
+	Limit  int
+	Period time.Duration
+}
+
+// Enabled сообщает, ограничивает ли политика запросы
+func (p Policy) Enabled() bool {
+	return p.Limit > 0 && p.Period > 0
+}
+
+// rate возвращает скорость пополнения корзины в токенах за наносекунду
+func (p Policy) rate() float64 {
+	return float64(p.Limit) / float64(p.Period)
+}
+
+// ParsePolicy разбирает политику в формате "лимит/период", например "60/1m".
+// Значение "off" или "0" отключает ограничение.
+func ParsePolicy(name, value string) (Policy, error) {
+	value = strings.TrimSpace(value)
+	if value == "off" || value == "0" {
+		return Policy{Name: name}, nil
+	}
+
+	limitValue, periodValue, ok := strings.Cut(value, "/")
+	if !ok {
+		return Policy{}, errors.New("ожидается формат \"лимит/период\"")
+	}
+
+	limit, err := strconv.Atoi(limitValue)
+	if err != nil || limit < 0 {
+		return Policy{}, fmt.Errorf("неверный лимит %q", limitValue)
+	}
+
+	period, err := time.ParseDuration(periodValue)
+	if err != nil || period <= 0 {
+		return Policy{}, fmt.Errorf("неверный период %q", periodValue)
+	}
+
+	return Policy{Name: name, Limit: limit, Period: period}, nil
+}
+
+// Decision представляет результат попытки забрать токен
+type Decision struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	RetryAfter time.Duration // через сколько появится следующий токен (если запрос отклонен)
+	ResetAfter time.Duration // через сколько корзина наполнится полностью
+}
+
+// Store хранит корзины токенов. Общее хранилище (например, Redis) позволяет
+// нескольким экземплярам сервера соблюдать одни и те же лимиты; Take должен быть атомарным.
+type Store interface {
+	Take(key string, policy Policy, now time.Time) (Decision, error)
+}
+
+// bucket — корзина токенов одного ключа
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	period  time.Duration
+}
+
+// sweepInterval определяет, как часто из памяти удаляются полные корзины
+const sweepInterval = time.Minute
+
+// MemoryStore хранит корзины в памяти процесса
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+// NewMemoryStore создает хранилище корзин в памяти
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: map[string]*bucket{}}
+}
+
+// Take забирает токен из корзины ключа
+func (s *MemoryStore) Take(key string, policy Policy, now time.Time) (Decision, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sweep(now)
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(policy.Limit), updated: now}
+		s.buckets[key] = b
+	}
+	b.period = policy.Period
+
+	// Пополняем корзину за время, прошедшее с последнего запроса
+	rate := policy.rate()
+	if elapsed := now.Sub(b.updated); elapsed > 0 {
+		b.tokens = math.Min(float64(policy.Limit), b.tokens+float64(elapsed)*rate)
+		b.updated = now
+	}
+
+	decision := Decision{Limit: policy.Limit}
+	if b.tokens >= 1 {
+		b.tokens--
+		decision.Allowed = true
+	} else {
+		decision.RetryAfter = time.Duration(math.Ceil((1 - b.tokens) / rate))
+	}
+	decision.Remaining = int(b.tokens)
+	decision.ResetAfter = time.Duration(math.Ceil((float64(policy.Limit) - b.tokens) / rate))
+
+	return decision, nil
+}
+
+// sweep удаляет корзины, которые успели наполниться полностью: они не отличаются от новых
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+
+	for key, b := range s.buckets {
+		if now.Sub(b.updated) >= b.period {
+			delete(s.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreTake(t *testing.T) {
+	policy := Policy{Name: "test", Limit: 3, Period: 3 * time.Second}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// Шаги выполняются по порядку на одном хранилище
+	type step struct {
+		key            string
+		at             time.Duration // время от начала
+		wantAllowed    bool
+		wantRemaining  int
+		wantRetryAfter time.Duration
+		wantResetAfter time.Duration
+	}
+
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name: "корзина расходуется и отклоняет запрос без токенов",
+			steps: []step{
+				{key: "a", wantAllowed: true, wantRemaining: 2, wantResetAfter: time.Second},
+				{key: "a", wantAllowed: true, wantRemaining: 1, wantResetAfter: 2 * time.Second},
+				{key: "a", wantAllowed: true, wantRemaining: 0, wantResetAfter: 3 * time.Second},
+				{key: "a", wantAllowed: false, wantRemaining: 0, wantRetryAfter: time.Second, wantResetAfter: 3 * time.Second},
+			},
+		},
+		{
+			name: "токены пополняются со временем",
+			steps: []step{
+				{key: "a", wantAllowed: true, wantRemaining: 2, wantResetAfter: time.Second},
+				{key: "a", wantAllowed: true, wantRemaining: 1, wantResetAfter: 2 * time.Second},
+				{key: "a", wantAllowed: true, wantRemaining: 0, wantResetAfter: 3 * time.Second},
+				{key: "a", at: 500 * time.Millisecond, wantAllowed: false, wantRemaining: 0,
+					wantRetryAfter: 500 * time.Millisecond, wantResetAfter: 2500 * time.Millisecond},
+				{key: "a", at: time.Second, wantAllowed: true, wantRemaining: 0, wantResetAfter: 3 * time.Second},
+			},
+		},
+		{
+			name: "корзина не наполняется больше лимита",
+			steps: []step{
+				{key: "a", wantAllowed: true, wantRemaining: 2, wantResetAfter: time.Second},
+				{key: "a", at: time.Hour, wantAllowed: true, wantRemaining: 2, wantResetAfter: time.Second},
+			},
+		},
+		{
+			name: "ключи учитываются раздельно",
+			steps: []step{
+				{key: "a", wantAllowed: true, wantRemaining: 2, wantResetAfter: time.Second},
+				{key: "a", wantAllowed: true, wantRemaining: 1, wantResetAfter: 2 * time.Second},
+				{key: "a", wantAllowed: true, wantRemaining: 0, wantResetAfter: 3 * time.Second},
+				{key: "b", wantAllowed: true, wantRemaining: 2, wantResetAfter: time.Second},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewMemoryStore()
+			for i, s := range tt.steps {
+				got, err := store.Take(s.key, policy, start.Add(s.at))
+				if err != nil {
+					t.Fatalf("шаг %d: Take() error = %v", i, err)
+				}
+				if got.Allowed != s.wantAllowed || got.Remaining != s.wantRemaining || got.Limit != policy.Limit ||
+					got.RetryAfter != s.wantRetryAfter || got.ResetAfter != s.wantResetAfter {
+					t.Errorf("шаг %d: Take() = %+v, want allowed=%v remaining=%d retry=%s reset=%s",
+						i, got, s.wantAllowed, s.wantRemaining, s.wantRetryAfter, s.wantResetAfter)
+				}
+			}
+		})
+	}
+}
+
+func TestMemoryStoreSweep(t *testing.T) {
+	policy := Policy{Name: "test", Limit: 1, Period: time.Second}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	store := NewMemoryStore()
+	if _, err := store.Take("a", policy, start); err != nil {
+		t.Fatal(err)
+	}
+	// Через интервал очистки корзина "a" наполнилась и удаляется при следующем запросе
+	if _, err := store.Take("b", policy, start.Add(sweepInterval)); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := store.buckets["a"]; ok {
+		t.Error("полная корзина не удалена")
+	}
+	if _, ok := store.buckets["b"]; !ok {
+		t.Error("корзина текущего запроса удалена")
+	}
+}
+
+func TestParsePolicy(t *testing.T) {
+	tests := []struct {
+		value       string
+		want        Policy
+		wantEnabled bool
+		wantErr     bool
+	}{
+		{value: "60/1m", want: Policy{Name: "p", Limit: 60, Period: time.Minute}, wantEnabled: true},
+		{value: " 10/30s ", want: Policy{Name: "p", Limit: 10, Period: 30 * time.Second}, wantEnabled: true},
+		{value: "off", want: Policy{Name: "p"}},
+		{value: "0", want: Policy{Name: "p"}},
+		{value: "60", wantErr: true},
+		{value: "x/1m", wantErr: true},
+		{value: "-1/1m", wantErr: true},
+		{value: "60/0s", wantErr: true},
+		{value: "60/minute", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParsePolicy("p", tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParsePolicy(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got != tt.want || got.Enabled() != tt.wantEnabled {
+				t.Errorf("ParsePolicy(%q) = %+v (enabled %v), want %+v (enabled %v)",
+					tt.value, got, got.Enabled(), tt.want, tt.wantEnabled)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package storage
+
+import (
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/google/uuid"
+)
+
+// Local хранит файлы в директории на диске. Сервер отдает их сам по адресу BaseURL.
+type Local struct {
+	Dir     string
+	BaseURL string
+}
+
+// NewLocal создает локальное хранилище и его директорию
+func NewLocal(dir, baseURL string) (*Local, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &Local{Dir: dir, BaseURL: baseURL}, nil
+}
+
+// Put записывает файл во временный и переименовывает его, чтобы файл не отдали недописанным
+func (s *Local) Put(name string, r io.Reader, contentType string) error {
+	if !validName(name) {
+		return errInvalidName(name)
+	}
+
+	tempPath := filepath.Join(s.Dir, "."+uuid.NewString()+".tmp")
+	file, err := os.Create(tempPath)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(file, r)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tempPath, filepath.Join(s.Dir, name))
+	}
+	if err != nil {
+		os.Remove(tempPath)
+	}
+	return err
+}
+
+// Get открывает файл для чтения
+func (s *Local) Get(name string) (io.ReadCloser, error) {
+	if !validName(name) {
+		return nil, os.ErrNotExist
+	}
+	return os.Open(filepath.Join(s.Dir, name))
+}
+
+// Delete удаляет файл
+func (s *Local) Delete(name string) error {
+	if !validName(name) {
+		return errInvalidName(name)
+	}
+	if err := os.Remove(filepath.Join(s.Dir, name)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Exists проверяет наличие файла
+func (s *Local) Exists(name string) (bool, error) {
+	if !validName(name) {
+		return false, nil
+	}
+	_, err := os.Stat(filepath.Join(s.Dir, name))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// List возвращает файлы директории. Временные файлы недописанных загрузок
+// (имена с точкой в начале) не возвращаются.
+func (s *Local) List() ([]FileInfo, error) {
+	entries, err := os.ReadDir(s.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]FileInfo, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || !validName(entry.Name()) {
+			continue
+		}
+		info, err := entry.Info()
+		if os.IsNotExist(err) {
+			continue // файл удален во время обхода
+		}
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, FileInfo{Name: entry.Name(), Size: info.Size(), ModTime: info.ModTime()})
+	}
+	return files, nil
+}
+
+// URL возвращает адрес файла на сервере
+func (s *Local) URL(name string) string {
+	return s.BaseURL + url.PathEscape(name)
+}
@@ -0,0 +1,160 @@
+package storage
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidName(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"card_abc.jpg", true},
+		{"фото 1.png", true},
+		{"", false},
+		{".hidden", false},
+		{".tmp", false},
+		{"..", false},
+		{"../secret", false},
+		{"dir/file.jpg", false},
+		{"/etc/passwd", false},
+		{`dir\file.jpg`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validName(tt.name); got != tt.want {
+				t.Errorf("validName(%q) = %v, want %v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+// checkingReader перед первым чтением вызывает check, а затем возвращает data и err
+type checkingReader struct {
+	check   func()
+	data    io.Reader
+	err     error
+	checked bool
+}
+
+func (r *checkingReader) Read(p []byte) (int, error) {
+	if !r.checked {
+		r.checked = true
+		r.check()
+	}
+	n, err := r.data.Read(p)
+	if err == io.EOF && r.err != nil {
+		return n, r.err
+	}
+	return n, err
+}
+
+func TestLocalPut(t *testing.T) {
+	errRead := errors.New("соединение оборвалось")
+
+	tests := []struct {
+		name     string
+		existing string // содержимое файла до Put, пусто — файла нет
+		readErr  error
+		want     string // содержимое после Put, пусто — файла нет
+	}{
+		{"новый файл", "", nil, "new"},
+		{"замена файла", "old", nil, "new"},
+		{"ошибка чтения не создает файл", "", errRead, ""},
+		{"ошибка чтения сохраняет прежний файл", "old", errRead, "old"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			local, err := NewLocal(t.TempDir(), "http://localhost/uploads/")
+			if err != nil {
+				t.Fatal(err)
+			}
+			path := filepath.Join(local.Dir, "a.jpg")
+			if tt.existing != "" {
+				if err := os.WriteFile(path, []byte(tt.existing), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			// Пока файл записывается, по имени доступно только прежнее содержимое,
+			// а временный файл не попадает в список файлов хранилища
+			reader := &checkingReader{data: strings.NewReader("new"), err: tt.readErr, check: func() {
+				data, err := os.ReadFile(path)
+				if tt.existing == "" && !os.IsNotExist(err) || tt.existing != "" && string(data) != tt.existing {
+					t.Errorf("во время записи файл = %q, %v, want %q", data, err, tt.existing)
+				}
+				files, err := local.List()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if tt.existing == "" && len(files) != 0 || tt.existing != "" && len(files) != 1 {
+					t.Errorf("во время записи List() = %v", files)
+				}
+			}}
+
+			err = local.Put("a.jpg", reader, "image/jpeg")
+			if !errors.Is(err, tt.readErr) {
+				t.Fatalf("Put() error = %v, want %v", err, tt.readErr)
+			}
+
+			data, err := os.ReadFile(path)
+			if tt.want == "" && !os.IsNotExist(err) || tt.want != "" && string(data) != tt.want {
+				t.Errorf("после Put файл = %q, %v, want %q", data, err, tt.want)
+			}
+
+			// Временные файлы не остаются ни после записи, ни после ошибки
+			entries, err := os.ReadDir(local.Dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, entry := range entries {
+				if entry.Name() != "a.jpg" {
+					t.Errorf("в директории остался файл %s", entry.Name())
+				}
+			}
+		})
+	}
+}
+
+func TestLocalInvalidNames(t *testing.T) {
+	dir := t.TempDir()
+	local, err := NewLocal(filepath.Join(dir, "uploads"), "http://localhost/uploads/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Файл рядом с директорией хранилища не должен быть доступен через него
+	if err := os.WriteFile(filepath.Join(dir, "secret"), []byte("secret"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"", ".hidden", "../secret", "dir/file.jpg", `..\secret`} {
+		t.Run(name, func(t *testing.T) {
+			if err := local.Put(name, strings.NewReader("data"), ""); err == nil {
+				t.Error("Put() error = nil, want error")
+			}
+			if _, err := local.Get(name); !errors.Is(err, os.ErrNotExist) {
+				t.Errorf("Get() error = %v, want %v", err, os.ErrNotExist)
+			}
+			if err := local.Delete(name); err == nil {
+				t.Error("Delete() error = nil, want error")
+			}
+			if exists, err := local.Exists(name); exists || err != nil {
+				t.Errorf("Exists() = %v, %v, want false, nil", exists, err)
+			}
+		})
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "secret")); err != nil {
+		t.Errorf("файл вне хранилища изменен: %v", err)
+	}
+	if entries, _ := os.ReadDir(local.Dir); len(entries) != 0 {
+		t.Errorf("в хранилище появились файлы: %v", entries)
+	}
+}
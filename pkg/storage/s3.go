@@ -0,0 +1,314 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultS3Region — регион S3 по умолчанию (MinIO и большинство совместимых хранилищ его принимают)
+const DefaultS3Region = "us-east-1"
+
+// defaultS3Client используется, если клиент не задан; время одного запроса к S3 ограничено
+var defaultS3Client = &http.Client{Timeout: 30 * time.Second}
+
+// S3 хранит файлы в S3-совместимом хранилище (AWS S3, MinIO и др.). Запросы подписываются
+// по AWS Signature V4, бакет адресуется в пути (http://endpoint/bucket/key), как принято в MinIO.
+type S3 struct {
+	Endpoint  string // например, http://localhost:9000
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	Prefix    string // префикс ключей, например "uploads/"
+	PublicURL string // публичный адрес, к которому добавляется имя файла; по умолчанию адрес объекта в бакете
+	Client    *http.Client
+}
+
+// Put загружает файл в бакет
+func (s *S3) Put(name string, r io.Reader, contentType string) error {
+	if !validName(name) {
+		return errInvalidName(name)
+	}
+
+	// Подпись требует хэш содержимого, а загружаемые изображения невелики, поэтому читаем их целиком
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	// Имена загруженных файлов определяются содержимым, поэтому файлы можно кэшировать бессрочно
+	header := http.Header{}
+	header.Set("Cache-Control", "public, max-age=31536000, immutable")
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+	resp, err := s.do(http.MethodPut, name, data, header)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return s.responseError(http.MethodPut, name, resp)
+	}
+	return nil
+}
+
+// Get открывает файл из бакета для чтения
+func (s *S3) Get(name string) (io.ReadCloser, error) {
+	if !validName(name) {
+		return nil, os.ErrNotExist
+	}
+
+	resp, err := s.do(http.MethodGet, name, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp.Body, nil
+	case http.StatusNotFound:
+		resp.Body.Close()
+		return nil, os.ErrNotExist
+	default:
+		defer resp.Body.Close()
+		return nil, s.responseError(http.MethodGet, name, resp)
+	}
+}
+
+// Delete удаляет файл из бакета
+func (s *S3) Delete(name string) error {
+	if !validName(name) {
+		return errInvalidName(name)
+	}
+
+	resp, err := s.do(http.MethodDelete, name, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNoContent, http.StatusNotFound:
+		return nil
+	default:
+		return s.responseError(http.MethodDelete, name, resp)
+	}
+}
+
+// Exists проверяет наличие файла в бакете
+func (s *S3) Exists(name string) (bool, error) {
+	if !validName(name) {
+		return false, nil
+	}
+
+	resp, err := s.do(http.MethodHead, name, nil, nil)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		return false, s.responseError(http.MethodHead, name, resp)
+	}
+}
+
+// URL возвращает публичный адрес файла
+func (s *S3) URL(name string) string {
+	if s.PublicURL != "" {
+		return s.PublicURL + url.PathEscape(name)
+	}
+	return strings.TrimSuffix(s.Endpoint, "/") + s.objectPath(name)
+}
+
+// List возвращает файлы бакета с префиксом Prefix. Ключи с "/" после префикса
+// не являются файлами хранилища и пропускаются.
+func (s *S3) List() ([]FileInfo, error) {
+	bucketPath := "/" + s3Escape(s.Bucket)
+	query := url.Values{"list-type": {"2"}}
+	if s.Prefix != "" {
+		query.Set("prefix", s.Prefix)
+	}
+
+	files := []FileInfo{}
+	for {
+		resp, err := s.request(http.MethodGet, bucketPath, query, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			err := s.responseError("LIST", s.Prefix, resp)
+			resp.Body.Close()
+			return nil, err
+		}
+
+		var result struct {
+			Contents []struct {
+				Key          string
+				Size         int64
+				LastModified time.Time
+			}
+			IsTruncated           bool
+			NextContinuationToken string
+		}
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("S3 LIST %s: %w", s.Prefix, err)
+		}
+
+		for _, object := range result.Contents {
+			name := strings.TrimPrefix(object.Key, s.Prefix)
+			if !validName(name) {
+				continue
+			}
+			files = append(files, FileInfo{Name: name, Size: object.Size, ModTime: object.LastModified})
+		}
+
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return files, nil
+		}
+		query.Set("continuation-token", result.NextContinuationToken)
+	}
+}
+
+// objectPath возвращает путь объекта, закодированный по правилам S3
+func (s *S3) objectPath(name string) string {
+	segments := strings.Split(s.Bucket+"/"+s.Prefix+name, "/")
+	for i, segment := range segments {
+		segments[i] = s3Escape(segment)
+	}
+	return "/" + strings.Join(segments, "/")
+}
+
+// do выполняет подписанный запрос к объекту
+func (s *S3) do(method, name string, body []byte, header http.Header) (*http.Response, error) {
+	return s.request(method, s.objectPath(name), nil, body, header)
+}
+
+// request выполняет подписанный запрос по закодированному пути с параметрами query
+func (s *S3) request(method, objectPath string, query url.Values, body []byte, header http.Header) (*http.Response, error) {
+	rawQuery := canonicalQuery(query)
+	target := strings.TrimSuffix(s.Endpoint, "/") + objectPath
+	if rawQuery != "" {
+		target += "?" + rawQuery
+	}
+
+	req, err := http.NewRequest(method, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	s.sign(req, objectPath, rawQuery, body, time.Now().UTC())
+
+	client := s.Client
+	if client == nil {
+		client = defaultS3Client
+	}
+	return client.Do(req)
+}
+
+// sign подписывает запрос по AWS Signature V4. Путь и параметры передаются уже закодированными
+// по правилам S3, так как net/url кодирует некоторые символы иначе.
+func (s *S3) sign(req *http.Request, objectPath, rawQuery string, body []byte, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := sha256Hex(body)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		objectPath,
+		rawQuery,
+		"host:" + req.URL.Host,
+		"x-amz-content-sha256:" + payloadHash,
+		"x-amz-date:" + amzDate,
+		"",
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + s.Region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+s.SecretKey), date)
+	key = hmacSHA256(key, s.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.AccessKey, scope, signedHeaders, signature))
+}
+
+// responseError формирует ошибку по ответу S3
+func (s *S3) responseError(method, name string, resp *http.Response) error {
+	message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf("S3 %s %s: %s %s", method, name, resp.Status, strings.TrimSpace(string(message)))
+}
+
+// canonicalQuery кодирует параметры запроса для подписи: ключи по порядку, ключи и значения
+// закодированы по правилам S3
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var pairs []string
+	for _, key := range keys {
+		values := append([]string(nil), query[key]...)
+		sort.Strings(values)
+		for _, value := range values {
+			pairs = append(pairs, s3Escape(key)+"="+s3Escape(value))
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+// s3Escape кодирует сегмент пути: S3 оставляет без кодирования только A-Z, a-z, 0-9 и -._~
+func s3Escape(segment string) string {
+	var b strings.Builder
+	for _, c := range []byte(segment) {
+		if ('A' <= c && c <= 'Z') || ('a' <= c && c <= 'z') || ('0' <= c && c <= '9') || strings.IndexByte("-._~", c) >= 0 {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,95 @@
+package storage
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// Storage — хранилище загруженных файлов. Имена файлов плоские, без директорий.
+type Storage interface {
+	// Put сохраняет файл, заменяя существующий с тем же именем
+	Put(name string, r io.Reader, contentType string) error
+	// Get открывает файл для чтения; для отсутствующего файла возвращает os.ErrNotExist
+	Get(name string) (io.ReadCloser, error)
+	// Delete удаляет файл; отсутствие файла ошибкой не считается
+	Delete(name string) error
+	// Exists проверяет наличие файла
+	Exists(name string) (bool, error)
+	// URL возвращает публичный адрес файла
+	URL(name string) string
+	// List возвращает все файлы хранилища
+	List() ([]FileInfo, error)
+}
+
+// FileInfo описывает файл в хранилище
+type FileInfo struct {
+	Name    string
+	Size    int64
+	ModTime time.Time
+}
+
+// Бэкенды хранилища
+const (
+	BackendLocal = "local"
+	BackendS3    = "s3"
+)
+
+// DefaultLocalDir — директория локального хранилища по умолчанию
+const DefaultLocalDir = "./uploads"
+
+// DefaultLocalURL — адрес, по которому сервер отдает файлы локального хранилища
+const DefaultLocalURL = "http://localhost:4000/uploads/"
+
+// FromEnv создает хранилище по переменной окружения STORAGE_BACKEND ("local" по умолчанию или "s3").
+// Локальное хранилище настраивается переменными STORAGE_LOCAL_DIR и STORAGE_PUBLIC_URL,
+// S3-совместимое — переменными S3_ENDPOINT, S3_REGION, S3_BUCKET, S3_ACCESS_KEY,
+// S3_SECRET_KEY, S3_PREFIX и STORAGE_PUBLIC_URL.
+func FromEnv() (Storage, error) {
+	backend := os.Getenv("STORAGE_BACKEND")
+	switch backend {
+	case "", BackendLocal:
+		return NewLocal(envOrDefault("STORAGE_LOCAL_DIR", DefaultLocalDir), envOrDefault("STORAGE_PUBLIC_URL", DefaultLocalURL))
+
+	case BackendS3:
+		s3 := &S3{
+			Endpoint:  os.Getenv("S3_ENDPOINT"),
+			Region:    envOrDefault("S3_REGION", DefaultS3Region),
+			Bucket:    os.Getenv("S3_BUCKET"),
+			AccessKey: os.Getenv("S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("S3_SECRET_KEY"),
+			Prefix:    os.Getenv("S3_PREFIX"),
+			PublicURL: os.Getenv("STORAGE_PUBLIC_URL"),
+		}
+		if s3.Endpoint == "" || s3.Bucket == "" || s3.AccessKey == "" || s3.SecretKey == "" {
+			return nil, fmt.Errorf("для хранилища S3 нужны S3_ENDPOINT, S3_BUCKET, S3_ACCESS_KEY и S3_SECRET_KEY")
+		}
+		log.Printf("Файлы сохраняются в S3: %s/%s", strings.TrimSuffix(s3.Endpoint, "/"), s3.Bucket)
+		return s3, nil
+
+	default:
+		return nil, fmt.Errorf("неизвестное хранилище %q, допустимо: %s, %s", backend, BackendLocal, BackendS3)
+	}
+}
+
+// envOrDefault возвращает значение переменной окружения или значение по умолчанию
+func envOrDefault(variable, fallback string) string {
+	if value := os.Getenv(variable); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// validName проверяет, что имя файла не содержит пути
+func validName(name string) bool {
+	return name != "" && name[0] != '.' && path.Base(name) == name && !strings.ContainsAny(name, `\`)
+}
+
+// errInvalidName возвращается для имен файлов с путем
+func errInvalidName(name string) error {
+	return fmt.Errorf("недопустимое имя файла %q", name)
+}
@@ -0,0 +1,176 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"log"
+	"net/http"
+	"os"
+	"runtime"
+	"strconv"
+	"time"
+)
+
+// Ограничения декодирования изображений по умолчанию
+const (
+	// DefaultMaxImagePixels — максимальное количество пикселей в кадре (40 мегапикселей)
+	DefaultMaxImagePixels = 40_000_000
+	// DefaultMaxImageFrames — максимальное количество кадров анимированного GIF
+	DefaultMaxImageFrames = 200
+	// DefaultDecodeTimeout — максимальное время декодирования одного изображения
+	DefaultDecodeTimeout = 10 * time.Second
+)
+
+// Текущие ограничения декодирования
+var (
+	MaxImagePixels = DefaultMaxImagePixels
+	MaxImageFrames = DefaultMaxImageFrames
+	DecodeTimeout  = DefaultDecodeTimeout
+)
+
+// decodeSlots ограничивает количество одновременных декодирований. Декодирование, прерванное
+// по времени, продолжается в фоне и занимает слот до завершения, поэтому поток тяжелых
+// изображений не может занять все ядра.
+var decodeSlots = make(chan struct{}, runtime.NumCPU())
+
+// LoadDecodeLimits задает ограничения декодирования из переменных окружения IMAGE_MAX_PIXELS,
+// IMAGE_MAX_FRAMES и IMAGE_DECODE_TIMEOUT. При неверном значении используется значение по умолчанию.
+func LoadDecodeLimits() {
+	MaxImagePixels = positiveIntEnv("IMAGE_MAX_PIXELS", DefaultMaxImagePixels)
+	MaxImageFrames = positiveIntEnv("IMAGE_MAX_FRAMES", DefaultMaxImageFrames)
+
+	if value := os.Getenv("IMAGE_DECODE_TIMEOUT"); value != "" {
+		timeout, err := time.ParseDuration(value)
+		if err != nil || timeout <= 0 {
+			log.Printf("Неверное значение IMAGE_DECODE_TIMEOUT %q, используем %s", value, DefaultDecodeTimeout)
+			timeout = DefaultDecodeTimeout
+		}
+		DecodeTimeout = timeout
+	}
+}
+
+// positiveIntEnv читает положительное целое число из переменной окружения
+func positiveIntEnv(variable string, fallback int) int {
+	value := os.Getenv(variable)
+	if value == "" {
+		return fallback
+	}
+
+	number, err := strconv.Atoi(value)
+	if err != nil || number <= 0 {
+		log.Printf("Неверное значение %s %q, используем %d", variable, value, fallback)
+		return fallback
+	}
+	return number
+}
+
+// checkImageConfig читает заголовок изображения без декодирования пикселей и проверяет,
+// что декодирование не потребует слишком много памяти: количество пикселей кадра
+// и количество кадров анимации не превышают ограничений
+func checkImageConfig(data []byte) (image.Config, string, error) {
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return config, format, &ImageError{Message: fmt.Sprintf("неподдерживаемый формат изображения: %s", http.DetectContentType(data))}
+	}
+
+	if config.Width <= 0 || config.Height <= 0 {
+		return config, format, &ImageError{Message: "неверные размеры изображения"}
+	}
+	// Сравниваем через деление, чтобы произведение размеров не переполнилось
+	if config.Width > MaxImagePixels/config.Height {
+		return config, format, &ImageError{Message: fmt.Sprintf("изображение %dx%d превышает допустимое количество пикселей (%d)",
+			config.Width, config.Height, MaxImagePixels)}
+	}
+
+	if format == "gif" {
+		if frames := gifFrameCount(data); frames > MaxImageFrames {
+			return config, format, &ImageError{Message: fmt.Sprintf("слишком много кадров анимации (%d), допустимо не больше %d",
+				frames, MaxImageFrames)}
+		}
+	}
+
+	return config, format, nil
+}
+
+// decodeImage полностью декодирует изображение после проверки заголовка. Если декодирование
+// не укладывается в DecodeTimeout, возвращается ошибка, а его результат отбрасывается.
+func decodeImage(data []byte) (image.Image, error) {
+	if _, _, err := checkImageConfig(data); err != nil {
+		return nil, err
+	}
+
+	timeout := time.NewTimer(DecodeTimeout)
+	defer timeout.Stop()
+
+	select {
+	case decodeSlots <- struct{}{}:
+	case <-timeout.C:
+		return nil, &ImageError{Message: "превышено время ожидания обработки изображения"}
+	}
+
+	type result struct {
+		img image.Image
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		defer func() { <-decodeSlots }()
+		img, _, err := image.Decode(bytes.NewReader(data))
+		done <- result{img, err}
+	}()
+
+	select {
+	case r := <-done:
+		if r.err != nil {
+			return nil, &ImageError{Message: fmt.Sprintf("ошибка декодирования изображения: %v", r.err)}
+		}
+		return r.img, nil
+	case <-timeout.C:
+		return nil, &ImageError{Message: "превышено время декодирования изображения"}
+	}
+}
+
+// gifFrameCount считает кадры GIF по блокам файла, не распаковывая данные изображений.
+// Обрезанный файл не считается ошибкой: его проверит полное декодирование.
+func gifFrameCount(data []byte) int {
+	// Заголовок (6 байт) и логический дескриптор экрана (7 байт)
+	if len(data) < 13 {
+		return 0
+	}
+	i := 13
+	if data[10]&0x80 != 0 {
+		i += 3 << (data[10]&0x07 + 1)
+	}
+
+	frames := 0
+	for i < len(data) {
+		switch data[i] {
+		case 0x21: // расширение: метка и подблоки
+			i += 2
+		case 0x2C: // дескриптор кадра, локальная палитра и минимальный размер кода LZW
+			if i+10 > len(data) {
+				return frames
+			}
+			frames++
+			flags := data[i+9]
+			i += 10
+			if flags&0x80 != 0 {
+				i += 3 << (flags&0x07 + 1)
+			}
+			i++
+		default: // конец файла или неизвестный блок
+			return frames
+		}
+
+		// Пропускаем подблоки данных до блока нулевой длины
+		for i < len(data) {
+			size := int(data[i])
+			i += 1 + size
+			if size == 0 {
+				break
+			}
+		}
+	}
+	return frames
+}
@@ -0,0 +1,68 @@
+package utils
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/gif"
+	"testing"
+)
+
+// testGIF кодирует GIF из frames кадров; с globalPalette кадры используют общую палитру,
+// иначе у каждого кадра своя
+func testGIF(t *testing.T, frames int, globalPalette bool) []byte {
+	t.Helper()
+
+	animation := &gif.GIF{LoopCount: 0}
+	for i := 0; i < frames; i++ {
+		frame := image.NewPaletted(image.Rect(0, 0, 4, 4), palette.Plan9)
+		if !globalPalette {
+			// Разные палитры вынуждают кодировщик записать локальную палитру кадра
+			frame.Palette = color.Palette{color.Black, color.Gray{Y: uint8(i)}}
+		}
+		frame.SetColorIndex(i%4, i%4, 1)
+		animation.Image = append(animation.Image, frame)
+		animation.Delay = append(animation.Delay, 10)
+	}
+	if globalPalette {
+		animation.Config = image.Config{ColorModel: color.Palette(palette.Plan9), Width: 4, Height: 4}
+	}
+
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, animation); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestGIFFrameCount(t *testing.T) {
+	animated := testGIF(t, 5, true)
+	// Первые кадры одинаковы, поэтому начало анимации совпадает с GIF из двух кадров без завершающего байта
+	twoFrames := testGIF(t, 2, true)
+	truncated := animated[:len(twoFrames)+3]
+
+	tests := []struct {
+		name string
+		data []byte
+		want int
+	}{
+		{"один кадр", testGIF(t, 1, true), 1},
+		{"кадры с общей палитрой", animated, 5},
+		{"кадры с локальными палитрами", testGIF(t, 7, false), 7},
+		{"много кадров", testGIF(t, 300, true), 300},
+		{"обрезанный файл", truncated, 2},
+		{"только заголовок", animated[:13], 0},
+		{"короче заголовка", animated[:10], 0},
+		{"не GIF", []byte("not a gif file at all"), 0},
+		{"пустые данные", nil, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := gifFrameCount(tt.data); got != tt.want {
+				t.Errorf("gifFrameCount() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package utils
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/user/roma/pkg/models"
+)
+
+// Операции построчного сравнения
+const (
+	DiffEqual  = "equal"
+	DiffInsert = "insert"
+	DiffDelete = "delete"
+)
+
+// MaxDiffCells — наибольший размер таблицы сравнения (произведение количества различающихся
+// строк двух текстов). Таблица занимает память пропорционально этому числу, поэтому разница
+// между большими, сильно различающимися текстами не строится.
+const MaxDiffCells = 1 << 20
+
+// ErrDiffTooLarge возвращается, если тексты слишком велики для построчного сравнения
+var ErrDiffTooLarge = errors.New("версии слишком сильно различаются для построчного сравнения")
+
+// DiffLines строит построчную разницу между двумя текстами на основе наибольшей общей
+// подпоследовательности строк. Совпадающие начало и конец текстов не сравниваются; если
+// оставшиеся части больше MaxDiffCells, возвращается ErrDiffTooLarge.
+func DiffLines(from, to string) ([]models.DiffLine, error) {
+	a := splitLines(from)
+	b := splitLines(to)
+
+	// Общие первые и последние строки не участвуют в сравнении
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	middleA := a[prefix : len(a)-suffix]
+	middleB := b[prefix : len(b)-suffix]
+	if len(middleA) > 0 && len(middleB) > MaxDiffCells/len(middleA) {
+		return nil, ErrDiffTooLarge
+	}
+
+	lines := make([]models.DiffLine, 0, len(a)+len(b)-prefix-suffix)
+	for _, text := range a[:prefix] {
+		lines = append(lines, models.DiffLine{Op: DiffEqual, Text: text})
+	}
+	lines = diffMiddle(lines, middleA, middleB)
+	for _, text := range a[len(a)-suffix:] {
+		lines = append(lines, models.DiffLine{Op: DiffEqual, Text: text})
+	}
+
+	return lines, nil
+}
+
+// diffMiddle добавляет к lines разницу между a и b по таблице общей подпоследовательности
+func diffMiddle(lines []models.DiffLine, a, b []string) []models.DiffLine {
+	// Строки заменяются номерами, чтобы длинные строки не сравнивались посимвольно в каждой ячейке
+	ids := map[string]int{}
+	intern := func(texts []string) []int {
+		result := make([]int, len(texts))
+		for i, text := range texts {
+			id, ok := ids[text]
+			if !ok {
+				id = len(ids)
+				ids[text] = id
+			}
+			result[i] = id
+		}
+		return result
+	}
+	x, y := intern(a), intern(b)
+
+	// lcs[i][j] — длина общей подпоследовательности для a[i:] и b[j:]
+	lcs := make([][]int32, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int32, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(x) && j < len(y) {
+		switch {
+		case x[i] == y[j]:
+			lines = append(lines, models.DiffLine{Op: DiffEqual, Text: a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, models.DiffLine{Op: DiffDelete, Text: a[i]})
+			i++
+		default:
+			lines = append(lines, models.DiffLine{Op: DiffInsert, Text: b[j]})
+			j++
+		}
+	}
+	for ; i < len(x); i++ {
+		lines = append(lines, models.DiffLine{Op: DiffDelete, Text: a[i]})
+	}
+	for ; j < len(y); j++ {
+		lines = append(lines, models.DiffLine{Op: DiffInsert, Text: b[j]})
+	}
+
+	return lines
+}
+
+// splitLines разбивает текст на строки, пустой текст не содержит строк
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
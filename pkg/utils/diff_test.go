@@ -0,0 +1,73 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/user/roma/pkg/models"
+)
+
+func TestDiffLines(t *testing.T) {
+	eq := func(text string) models.DiffLine { return models.DiffLine{Op: DiffEqual, Text: text} }
+	ins := func(text string) models.DiffLine { return models.DiffLine{Op: DiffInsert, Text: text} }
+	del := func(text string) models.DiffLine { return models.DiffLine{Op: DiffDelete, Text: text} }
+
+	tests := []struct {
+		name     string
+		from, to string
+		want     []models.DiffLine
+	}{
+		{"оба текста пусты", "", "", []models.DiffLine{}},
+		{"добавление в пустой текст", "", "a\nb", []models.DiffLine{ins("a"), ins("b")}},
+		{"удаление всего текста", "a\nb\n", "", []models.DiffLine{del("a"), del("b")}},
+		{"без изменений", "a\nb\nc", "a\nb\nc", []models.DiffLine{eq("a"), eq("b"), eq("c")}},
+		{"замена строки в середине", "a\nb\nc", "a\nx\nc", []models.DiffLine{eq("a"), del("b"), ins("x"), eq("c")}},
+		{"вставка и удаление", "a\nb\nc\nd", "b\nc\nd\ne", []models.DiffLine{del("a"), eq("b"), eq("c"), eq("d"), ins("e")}},
+		{"перевод строки CRLF и завершающий перевод", "a\r\nb\r\n", "a\nb", []models.DiffLine{eq("a"), eq("b")}},
+		{"повторяющиеся строки", "a\na\nb", "a\nb\nb", []models.DiffLine{eq("a"), del("a"), ins("b"), eq("b")}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DiffLines(tt.from, tt.to)
+			if err != nil {
+				t.Fatalf("DiffLines() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DiffLines() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffLinesTooLarge(t *testing.T) {
+	lines := func(prefix string, n int) string {
+		result := make([]string, n)
+		for i := range result {
+			result[i] = fmt.Sprintf("%s%d", prefix, i)
+		}
+		return strings.Join(result, "\n")
+	}
+
+	tests := []struct {
+		name     string
+		from, to string
+		wantErr  bool
+	}{
+		{"полностью разные большие тексты", lines("a", 1100), lines("b", 1100), true},
+		{"общее начало не учитывается в размере", lines("a", 5000), lines("a", 5000) + "\nb", false},
+		{"таблица ровно на пределе", lines("a", 1024), lines("b", 1024), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := DiffLines(tt.from, tt.to)
+			if gotErr := errors.Is(err, ErrDiffTooLarge); gotErr != tt.wantErr {
+				t.Errorf("DiffLines() error = %v, want ErrDiffTooLarge: %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/user/roma/pkg/models"
+)
+
+// ParseEntities находит в тексте упоминания (@login) и хештеги (#тег).
+// Упоминание и хештег должны начинаться в начале текста или после символа,
+// не являющегося буквой, цифрой или подчеркиванием.
+func ParseEntities(text string) []models.TextEntity {
+	runes := []rune(text)
+	entities := []models.TextEntity{}
+
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		if r != '@' && r != '#' {
+			continue
+		}
+		if i > 0 && isWordRune(runes[i-1]) {
+			continue
+		}
+
+		// Логины состоят из латинских букв, цифр и подчеркиваний,
+		// хештеги могут содержать буквы любого алфавита
+		end := i + 1
+		for end < len(runes) {
+			if r == '@' && !isLoginRune(runes[end]) || r == '#' && !isWordRune(runes[end]) {
+				break
+			}
+			end++
+		}
+		if end == i+1 {
+			continue
+		}
+
+		value := string(runes[i+1 : end])
+		if r == '#' {
+			// Хештег из одних цифр (например, #1) не считается тегом
+			if strings.IndexFunc(value, unicode.IsLetter) < 0 {
+				i = end - 1
+				continue
+			}
+			entities = append(entities, models.TextEntity{
+				Type: models.EntityHashtag, Value: value, Start: i, End: end,
+			})
+		} else {
+			entities = append(entities, models.TextEntity{
+				Type: models.EntityMention, Value: value, Start: i, End: end,
+			})
+		}
+		i = end - 1
+	}
+
+	return entities
+}
+
+// isWordRune проверяет, является ли символ буквой, цифрой или подчеркиванием
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
+
+// isLoginRune проверяет, допустим ли символ в логине
+func isLoginRune(r rune) bool {
+	return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_'
+}
@@ -0,0 +1,45 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/user/roma/pkg/models"
+)
+
+func TestParseEntities(t *testing.T) {
+	mention := func(value string, start, end int) models.TextEntity {
+		return models.TextEntity{Type: models.EntityMention, Value: value, Start: start, End: end}
+	}
+	hashtag := func(value string, start, end int) models.TextEntity {
+		return models.TextEntity{Type: models.EntityHashtag, Value: value, Start: start, End: end}
+	}
+
+	tests := []struct {
+		name string
+		text string
+		want []models.TextEntity
+	}{
+		{"пустой текст", "", []models.TextEntity{}},
+		{"упоминание в начале", "@alice привет", []models.TextEntity{mention("alice", 0, 6)}},
+		{"упоминание и хештег", "hi @bob_1 #go", []models.TextEntity{mention("bob_1", 3, 9), hashtag("go", 10, 13)}},
+		{"смещения в символах, а не байтах", "Привет @bob", []models.TextEntity{mention("bob", 7, 11)}},
+		{"хештег кириллицей", "#котики и #cats", []models.TextEntity{hashtag("котики", 0, 7), hashtag("cats", 10, 15)}},
+		{"логин заканчивается на не латинском символе", "@alice, @bobпривет", []models.TextEntity{mention("alice", 0, 6), mention("bob", 8, 12)}},
+		{"после буквы не считается", "mail@example.com a#b", []models.TextEntity{}},
+		{"после подчеркивания не считается", "_@alice", []models.TextEntity{}},
+		{"после пунктуации считается", "(@alice)", []models.TextEntity{mention("alice", 1, 7)}},
+		{"одиночные знаки", "@ # @@ ##", []models.TextEntity{}},
+		{"хештег из цифр", "#1 #2024год", []models.TextEntity{hashtag("2024год", 3, 11)}},
+		{"цифры хештега не дают упоминания", "#123@bob", []models.TextEntity{}},
+		{"второй знак после первого", "@@alice ##go", []models.TextEntity{mention("alice", 1, 7), hashtag("go", 9, 12)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseEntities(tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseEntities(%q) = %+v, want %+v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
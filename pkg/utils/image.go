@@ -2,133 +2,326 @@ package utils
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"encoding/base64"
-	"errors"
+	"encoding/hex"
 	"fmt"
+	"hash/fnv"
 	"image"
-	"net/http"
+	"io"
+	"log"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
+	"time"
+	"unicode"
 
 	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
 
-	"github.com/google/uuid"
+	_ "golang.org/x/image/webp"
+
+	"github.com/user/roma/pkg/storage"
 )
 
 const (
 	// Максимальный размер изображения (5MB)
 	MaxImageSize = 5 * 1024 * 1024
-	// Директория для сохранения изображений
-	ImageDir = "./uploads"
+	// Максимальное количество изображений в галерее карточки
+	MaxCardImages = 10
 	// Максимальные размеры для баннера профиля (10000 позволяет загружать практически любой размер)
 	MaxBannerWidth  = 10000
 	MaxBannerHeight = 10000
 )
 
-// SaveImageFromBase64 сохраняет изображение из base64 строки
-func SaveImageFromBase64(base64String, prefix string) (string, error) {
-	// Создаем директорию для загрузок, если ее нет
-	if err := os.MkdirAll(ImageDir, 0755); err != nil {
-		return "", err
-	}
+// Store — хранилище загруженных изображений, задается при запуске сервера
+var Store storage.Storage = &storage.Local{Dir: storage.DefaultLocalDir, BaseURL: storage.DefaultLocalURL}
 
-	// Если строка пустая
-	if base64String == "" {
-		return "", errors.New("пустая строка base64")
+// ImageRefCounter учитывает ссылки на файлы изображений. Одинаковые изображения хранятся
+// одним файлом, и файл удаляется только вместе с последней ссылкой на него.
+type ImageRefCounter interface {
+	// Count возвращает количество ссылок на файл
+	Count(filename string) (int, error)
+	// Add добавляет ссылку на файл
+	Add(filename, hash string, size int) error
+	// Release убирает ссылку на файл и возвращает количество оставшихся ссылок
+	Release(filename string) (int, error)
+	// Forget удаляет учет ссылок на неиспользуемый файл, если ссылка не добавлялась после before;
+	// возвращает false, если файл недавно использовался
+	Forget(filename string, before time.Time) (bool, error)
+}
+
+// Refs — учет ссылок на файлы изображений, задается при запуске сервера.
+// Без него каждое удаление сразу удаляет файл.
+var Refs ImageRefCounter
+
+// imageLocks упорядочивают сохранение и удаление файла с одним именем, чтобы удаление
+// последней ссылки не удалило файл, который в это же время загружается повторно
+var imageLocks [64]sync.Mutex
+
+// lockImage блокирует операции с файлом изображения и возвращает функцию разблокировки
+func lockImage(filename string) func() {
+	hash := fnv.New32a()
+	hash.Write([]byte(filename))
+	lock := &imageLocks[hash.Sum32()%uint32(len(imageLocks))]
+	lock.Lock()
+	return lock.Unlock
+}
+
+// ImageKind описывает вид загружаемого изображения: префикс имени файла, ограничения размеров
+// и производные размеры, создаваемые при загрузке
+type ImageKind struct {
+	Prefix    string
+	MaxWidth  int // 0 — без ограничения
+	MaxHeight int
+	Sizes     []ImageSize
+}
+
+// Виды загружаемых изображений
+var (
+	CardImage = ImageKind{
+		Prefix: "card",
+		Sizes:  []ImageSize{{Width: 640}, {Width: 1280}},
+	}
+	ProfileImage = ImageKind{
+		Prefix: "profile",
+		Sizes:  []ImageSize{{Width: 150, Height: 150}},
+	}
+	BannerImage = ImageKind{
+		Prefix:    "banner",
+		MaxWidth:  MaxBannerWidth,
+		MaxHeight: MaxBannerHeight,
+		Sizes:     []ImageSize{{Width: 600, Height: 200}, {Width: 1200, Height: 400}},
 	}
+)
 
-	// Декодируем base64 строку
-	var data []byte
-	var err error
+// imageExtensions сопоставляет форматы, которые умеет декодировать сервер, с расширениями файлов
+var imageExtensions = map[string]string{
+	"jpeg": ".jpg",
+	"png":  ".png",
+	"gif":  ".gif",
+	"webp": ".webp",
+}
 
-	// Проверяем наличие префикса data URL
-	if idx := strings.Index(base64String, ";base64,"); idx > 0 {
-		// Получаем часть после ";base64,"
-		base64String = base64String[idx+8:]
-	} else if strings.HasPrefix(base64String, "data:") {
-		// Другой формат data URL, ищем первую запятую
-		if idx := strings.Index(base64String, ","); idx > 0 {
-			base64String = base64String[idx+1:]
-		}
+// ImageError описывает ошибку в самом изображении (размер, формат, повреждение),
+// в отличие от ошибок сервера при его сохранении
+type ImageError struct {
+	Message string
+}
+
+func (e *ImageError) Error() string {
+	return e.Message
+}
+
+// SaveImage читает изображение, проверяет его декодированием и сохраняет в хранилище
+// под именем из хэша SHA-256 содержимого. Если такое изображение уже сохранено, файл
+// не записывается повторно, а добавляется ссылка на него; каждому сохранению должно
+// соответствовать одно удаление через RemoveImage. Изображение читается в память
+// не больше MaxImageSize байт, без временных файлов.
+func SaveImage(r io.Reader, kind ImageKind) (string, error) {
+	// Читаем на байт больше допустимого, чтобы обнаружить превышение размера
+	data, err := io.ReadAll(io.LimitReader(r, MaxImageSize+1))
+	if err != nil {
+		return "", &ImageError{Message: fmt.Sprintf("ошибка чтения изображения: %v", err)}
+	}
+	if len(data) == 0 {
+		return "", &ImageError{Message: "пустое изображение"}
+	}
+	if len(data) > MaxImageSize {
+		return "", &ImageError{Message: "размер изображения превышает максимально допустимый"}
 	}
 
-	// Удаляем все пробелы и переносы строк, которые могут быть в строке
-	base64String = strings.ReplaceAll(base64String, " ", "")
-	base64String = strings.ReplaceAll(base64String, "\n", "")
-	base64String = strings.ReplaceAll(base64String, "\r", "")
-	base64String = strings.ReplaceAll(base64String, "\t", "")
+	// Проверяем формат, размеры и количество кадров по заголовку до полного декодирования
+	config, format, err := checkImageConfig(data)
+	if err != nil {
+		return "", err
+	}
+	ext, ok := imageExtensions[format]
+	if !ok {
+		return "", &ImageError{Message: fmt.Sprintf("неподдерживаемый формат изображения: %s", format)}
+	}
+	if (kind.MaxWidth > 0 && config.Width > kind.MaxWidth) || (kind.MaxHeight > 0 && config.Height > kind.MaxHeight) {
+		return "", &ImageError{Message: fmt.Sprintf("размер изображения превышает максимально допустимый (%dx%d)",
+			kind.MaxWidth, kind.MaxHeight)}
+	}
 
-	// Декодируем base64
-	data, err = base64.StdEncoding.DecodeString(base64String)
+	// Полностью декодируем изображение, чтобы отсеять поврежденные файлы
+	img, err := decodeImage(data)
 	if err != nil {
-		return "", fmt.Errorf("ошибка декодирования base64: %v", err)
+		return "", err
 	}
 
-	// Проверяем размер изображения
-	if len(data) > MaxImageSize {
-		return "", errors.New("размер изображения превышает максимально допустимый")
-	}
-
-	// Определяем формат изображения
-	contentType := http.DetectContentType(data)
-	var ext string
-	switch contentType {
-	case "image/jpeg":
-		ext = ".jpg"
-	case "image/png":
-		ext = ".png"
-	case "image/gif":
-		ext = ".gif"
-	case "image/webp":
-		ext = ".webp"
-	default:
-		return "", fmt.Errorf("неподдерживаемый формат изображения: %s", contentType)
-	}
-
-	// Генерируем уникальное имя файла
-	filename := fmt.Sprintf("%s_%s%s", prefix, uuid.NewString(), ext)
-	filepath := filepath.Join(ImageDir, filename)
-
-	// Проверяем размеры изображения, если это баннер профиля
-	if prefix == "banner" {
-		img, _, err := image.Decode(bytes.NewReader(data))
+	// Удаляем метаданные (в том числе координаты съемки) и поворачиваем изображение по EXIF
+	data, img, err = stripMetadata(data, format, img)
+	if err != nil {
+		return "", fmt.Errorf("ошибка удаления метаданных: %v", err)
+	}
+
+	// Имя файла определяется содержимым, поэтому одинаковые изображения хранятся одним файлом
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+	filename := fmt.Sprintf("%s_%s%s", kind.Prefix, hash, ext)
+
+	unlock := lockImage(filename)
+	defer unlock()
+
+	// Изображение уже сохранено: добавляем ссылку на существующий файл
+	if Refs != nil {
+		refs, err := Refs.Count(filename)
 		if err != nil {
-			return "", fmt.Errorf("ошибка декодирования изображения: %v", err)
+			return "", fmt.Errorf("ошибка проверки файла изображения: %v", err)
 		}
-		bounds := img.Bounds()
-		if bounds.Dx() > MaxBannerWidth || bounds.Dy() > MaxBannerHeight {
-			return "", fmt.Errorf("размер баннера превышает максимально допустимый (%dx%d)",
-				MaxBannerWidth, MaxBannerHeight)
+		if refs > 0 {
+			if err := Refs.Add(filename, hash, len(data)); err != nil {
+				return "", fmt.Errorf("ошибка учета файла изображения: %v", err)
+			}
+			return filename, nil
 		}
 	}
 
-	// Сохраняем изображение в файл
-	if err := os.WriteFile(filepath, data, 0644); err != nil {
-		return "", fmt.Errorf("ошибка сохранения файла: %v", err)
+	if err := saveImageFiles(filename, data, format, img, kind); err != nil {
+		removeImageFiles(filename)
+		return "", err
+	}
+
+	if Refs != nil {
+		if err := Refs.Add(filename, hash, len(data)); err != nil {
+			removeImageFiles(filename)
+			return "", fmt.Errorf("ошибка учета файла изображения: %v", err)
+		}
 	}
 
-	// Возвращаем относительный путь к изображению
 	return filename, nil
 }
 
-// RemoveImage удаляет изображение по имени файла
+// saveImageFiles сохраняет в хранилище изображение, его копию в WebP и уменьшенные копии
+func saveImageFiles(filename string, data []byte, format string, img image.Image, kind ImageKind) error {
+	if err := Store.Put(filename, bytes.NewReader(data), "image/"+format); err != nil {
+		return fmt.Errorf("ошибка сохранения файла: %v", err)
+	}
+
+	// Сохраняем копию в WebP для клиентов, которые его поддерживают. Кодировщик nativewebp
+	// умеет только WebP без потерь, который для фотографий больше JPEG, поэтому JPEG
+	// остаются без копии; для них нужен кодировщик WebP с потерями.
+	if format == "png" {
+		if err := saveWebPAlternative(filename, len(data), img); err != nil {
+			return fmt.Errorf("ошибка создания копии WebP: %v", err)
+		}
+	}
+
+	// Создаем уменьшенные копии для быстрой загрузки на клиентах
+	if err := saveDerivatives(img, filename, kind.Sizes); err != nil {
+		return fmt.Errorf("ошибка создания уменьшенных копий: %v", err)
+	}
+	return nil
+}
+
+// SaveImageFromBase64 сохраняет изображение из base64 строки или data URL
+func SaveImageFromBase64(base64String string, kind ImageKind) (string, error) {
+	// Проверяем наличие префикса data URL и отбрасываем его
+	if strings.HasPrefix(base64String, "data:") {
+		idx := strings.Index(base64String, ",")
+		if idx < 0 {
+			return "", &ImageError{Message: "неверный формат data URL"}
+		}
+		base64String = base64String[idx+1:]
+	}
+
+	// Удаляем все пробелы и переносы строк, которые могут быть в строке
+	base64String = strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, base64String)
+
+	if base64String == "" {
+		return "", &ImageError{Message: "пустая строка base64"}
+	}
+
+	// Декодируем base64 потоком при чтении изображения
+	return SaveImage(base64.NewDecoder(base64.StdEncoding, strings.NewReader(base64String)), kind)
+}
+
+// RemoveImage убирает ссылку на изображение. Файл вместе с уменьшенными копиями
+// удаляется, когда на него не остается ссылок.
 func RemoveImage(filename string) error {
 	if filename == "" {
 		return nil
 	}
 
-	filepath := filepath.Join(ImageDir, filename)
-	return os.Remove(filepath)
+	unlock := lockImage(filename)
+	defer unlock()
+
+	if Refs != nil {
+		refs, err := Refs.Release(filename)
+		if err != nil {
+			return err
+		}
+		if refs > 0 {
+			return nil
+		}
+	}
+	return removeImageFiles(filename)
+}
+
+// removeImageFiles удаляет файл изображения, его копию в WebP и уменьшенные копии
+func removeImageFiles(filename string) error {
+	removeDerivatives(filename)
+	if err := removeVariant(WebPAlternative(filename)); err != nil {
+		return err
+	}
+	return Store.Delete(filename)
 }
 
-// ImageExists проверяет существование изображения
+// ImageExists проверяет существование изображения в хранилище
 func ImageExists(filename string) bool {
-	if filename == "" {
+	if !isUploadName(filename) {
 		return false
 	}
 
-	filepath := filepath.Join(ImageDir, filename)
-	_, err := os.Stat(filepath)
-	return err == nil
+	exists, err := Store.Exists(filename)
+	if err != nil {
+		log.Printf("Ошибка проверки изображения %s: %v", filename, err)
+	}
+	return exists
+}
+
+// OpenImage открывает изображение из хранилища для чтения
+func OpenImage(filename string) (io.ReadCloser, error) {
+	return Store.Get(filename)
+}
+
+// OpenImageForClient открывает изображение для отдачи клиенту: если клиент поддерживает WebP
+// и у изображения есть копия в WebP, открывается она. Возвращает имя открытого файла.
+func OpenImageForClient(filename string, acceptsWebP bool) (io.ReadCloser, string, error) {
+	if !isUploadName(filename) {
+		return nil, "", os.ErrNotExist
+	}
+	if acceptsWebP && variantExists(WebPAlternative(filename)) {
+		// Копию мог удалить другой сервер: тогда отдаем оригинал
+		reader, err := Store.Get(WebPAlternative(filename))
+		if err == nil {
+			return reader, WebPAlternative(filename), nil
+		}
+		forgetVariant(WebPAlternative(filename))
+	}
+
+	reader, err := Store.Get(filename)
+	return reader, filename, err
+}
+
+// ImageURL возвращает публичный адрес изображения или пустую строку, если изображения нет
+func ImageURL(filename string) string {
+	if filename == "" {
+		return ""
+	}
+	return Store.URL(filename)
+}
+
+// isUploadName проверяет, что имя указывает на файл непосредственно в директории загрузок
+func isUploadName(filename string) bool {
+	return filename != "" && filename[0] != '.' && filepath.Base(filename) == filename
 }
@@ -1,6 +1,9 @@
 package utils
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"os"
 	"time"
@@ -76,3 +79,12 @@ func getSecretKey() string {
 	}
 	return key
 }
+
+// KeyedHash возвращает HMAC-SHA256 значения на секретном ключе сервера. В отличие от
+// простого хеша, значение с небольшим числом вариантов (например, IP-адрес) нельзя
+// восстановить перебором, не зная ключа.
+func KeyedHash(value string) string {
+	mac := hmac.New(sha256.New, []byte(getSecretKey()))
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,75 @@
+package utils
+
+import (
+	"bytes"
+	"regexp"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+// markdown преобразует текст карточки по CommonMark с расширениями GFM
+// (таблицы, зачеркивание, автоссылки, списки задач). Сырой HTML в тексте не выводится.
+var markdown = goldmark.New(
+	goldmark.WithExtensions(extension.GFM),
+)
+
+// htmlPolicy перечисляет теги и атрибуты, которые разрешено отдавать клиентам.
+// Все остальное (скрипты, обработчики событий, стили, небезопасные ссылки) удаляется.
+var htmlPolicy = newHTMLPolicy()
+
+// newHTMLPolicy создает allowlist-политику для HTML, полученного из Markdown
+func newHTMLPolicy() *bluemonday.Policy {
+	p := bluemonday.NewPolicy()
+
+	// Текстовые блоки и форматирование
+	p.AllowElements("p", "br", "hr", "h1", "h2", "h3", "h4", "h5", "h6",
+		"blockquote", "pre", "em", "strong", "del", "ul", "li")
+	p.AllowAttrs("start").Matching(bluemonday.Integer).OnElements("ol")
+	p.AllowElements("ol")
+
+	// Блоки кода с указанием языка
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^language-[\w+#-]+$`)).OnElements("code")
+	p.AllowElements("code")
+
+	// Таблицы
+	p.AllowElements("table", "thead", "tbody", "tr")
+	p.AllowAttrs("align").Matching(regexp.MustCompile(`^(left|center|right)$`)).OnElements("th", "td")
+	p.AllowStyles("text-align").MatchingEnum("left", "center", "right").OnElements("th", "td")
+	p.AllowElements("th", "td")
+
+	// Списки задач
+	p.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	p.AllowAttrs("checked", "disabled").Matching(regexp.MustCompile(`^$`)).OnElements("input")
+
+	// Ссылки и изображения только с безопасными схемами
+	p.AllowStandardURLs()
+	p.AllowURLSchemes("http", "https", "mailto")
+	p.AllowAttrs("href", "title").OnElements("a")
+	p.AllowAttrs("src", "alt", "title").OnElements("img")
+	p.RequireNoFollowOnLinks(true)
+	p.AddTargetBlankToFullyQualifiedLinks(true)
+
+	return p
+}
+
+// RenderMarkdown преобразует Markdown в безопасный HTML
+func RenderMarkdown(text string) string {
+	if text == "" {
+		return ""
+	}
+
+	var buf bytes.Buffer
+	if err := markdown.Convert([]byte(text), &buf); err != nil {
+		// При ошибке разбора отдаем экранированный текст
+		return htmlPolicy.Sanitize("<p>" + bluemonday.StrictPolicy().Sanitize(text) + "</p>")
+	}
+
+	return SanitizeHTML(buf.String())
+}
+
+// SanitizeHTML удаляет из HTML все, что не входит в allowlist
+func SanitizeHTML(html string) string {
+	return htmlPolicy.Sanitize(html)
+}
@@ -0,0 +1,70 @@
+package utils
+
+import "testing"
+
+func TestRenderMarkdown(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"пустой текст", "", ""},
+		{"скрипт не выводится", "<script>alert(1)</script>", "\n"},
+		{"сырой HTML не выводится", `hi <b onclick="x()">b</b> <span>s</span>`, "<p>hi b s</p>\n"},
+		{"ссылка javascript:", "[x](javascript:alert(1))", "<p>x</p>\n"},
+		{"ссылка data:", "[x](data:text/html,hi)", "<p>x</p>\n"},
+		{"изображение data:", "![i](data:image/png;base64,AAA)", "<p><img alt=\"i\"></p>\n"},
+		{"изображение javascript:", "![i](javascript:alert(1))", "<p><img alt=\"i\"></p>\n"},
+		{"изображение https", `![i](https://a.com/i.png "t")`, "<p><img src=\"https://a.com/i.png\" alt=\"i\" title=\"t\"></p>\n"},
+		{"внешняя ссылка", "[x](https://a.com)", "<p><a href=\"https://a.com\" rel=\"nofollow noopener\" target=\"_blank\">x</a></p>\n"},
+		{"автоссылка", "<https://a.com>", "<p><a href=\"https://a.com\" rel=\"nofollow noopener\" target=\"_blank\">https://a.com</a></p>\n"},
+		{"относительная ссылка без target", "[x](/local)", "<p><a href=\"/local\" rel=\"nofollow\">x</a></p>\n"},
+		{"ссылка mailto:", "[m](mailto:a@b.c)", "<p><a href=\"mailto:a@b.c\" rel=\"nofollow\">m</a></p>\n"},
+		{"выравнивание в таблице", "| a | b |\n|:-|-:|\n| 1 | 2 |",
+			"<table>\n<thead>\n<tr>\n<th style=\"text-align: left\">a</th>\n<th style=\"text-align: right\">b</th>\n</tr>\n</thead>\n" +
+				"<tbody>\n<tr>\n<td style=\"text-align: left\">1</td>\n<td style=\"text-align: right\">2</td>\n</tr>\n</tbody>\n</table>\n"},
+		{"список задач", "- [x] done\n- [ ] todo",
+			"<ul>\n<li><input checked=\"\" disabled=\"\" type=\"checkbox\"> done</li>\n<li><input disabled=\"\" type=\"checkbox\"> todo</li>\n</ul>\n"},
+		{"язык блока кода", "```go\nx\n```", "<pre><code class=\"language-go\">x\n</code></pre>\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RenderMarkdown(tt.in); got != tt.want {
+				t.Errorf("RenderMarkdown(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSanitizeHTML(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"скрипт", `<p>a<script>alert(1)</script></p>`, "<p>a</p>"},
+		{"iframe", `<iframe src="https://a.com"></iframe>`, ""},
+		{"обработчик on* у изображения", `<img src="https://a.com/i.png" onerror="alert(1)">`, `<img src="https://a.com/i.png">`},
+		{"обработчик on* у списка", `<ol start="3" onclick="x()"><li>a</li></ol>`, `<ol start="3"><li>a</li></ol>`},
+		{"ссылка javascript:", `<a href="javascript:alert(1)">x</a>`, "x"},
+		{"изображение data:", `<img src="data:image/png;base64,AAA" alt="i">`, `<img alt="i">`},
+		{"target и rel ссылки заменяются", `<a href="https://a.com" target="_self" rel="opener">x</a>`,
+			`<a href="https://a.com" rel="nofollow noopener" target="_blank">x</a>`},
+		{"из стилей ячейки остается только выравнивание", `<td style="text-align: center; color: red">x</td>`, `<td style="text-align: center">x</td>`},
+		{"другие стили ячейки удаляются", `<th style="color: red">x</th>`, "<th>x</th>"},
+		{"стили вне таблицы удаляются", `<p style="text-align: center">x</p>`, "<p>x</p>"},
+		{"недопустимое выравнивание", `<td align="justify">x</td>`, "<td>x</td>"},
+		{"флажок списка задач", `<input type="checkbox" checked="" disabled="">`, `<input type="checkbox" checked="" disabled="">`},
+		{"поле ввода другого типа", `<input type="text" value="x" checked="yes" onfocus="x()">`, ""},
+		{"недопустимый класс блока кода", `<code class="language-go onclick">x</code>`, "<code>x</code>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SanitizeHTML(tt.in); got != tt.want {
+				t.Errorf("SanitizeHTML(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,214 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"os"
+)
+
+// OriginalJPEGQuality определяет качество JPEG при пересохранении загруженных оригиналов
+const OriginalJPEGQuality = 92
+
+// KeepColorProfile определяет, сохранять ли цветовой профиль ICC при удалении метаданных
+var KeepColorProfile = false
+
+// LoadMetadataOptions читает настройки удаления метаданных из переменной окружения
+// IMAGE_KEEP_COLOR_PROFILE ("true" сохраняет цветовой профиль)
+func LoadMetadataOptions() {
+	KeepColorProfile = os.Getenv("IMAGE_KEEP_COLOR_PROFILE") == "true"
+}
+
+// stripMetadata пересохраняет изображение без метаданных (EXIF, XMP, текстовые блоки PNG),
+// предварительно повернув его согласно ориентации из EXIF. Возвращает новые данные файла
+// и повернутое изображение. Из WebP метаданные удаляются без перекодирования.
+// GIF не содержит EXIF и сохраняется как есть, чтобы не терять анимацию.
+func stripMetadata(data []byte, format string, img image.Image) ([]byte, image.Image, error) {
+	var buf bytes.Buffer
+	switch format {
+	case "jpeg":
+		img = applyOrientation(img, jpegOrientation(data))
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: OriginalJPEGQuality}); err != nil {
+			return nil, nil, err
+		}
+		if KeepColorProfile {
+			return insertAfter(buf.Bytes(), 2, jpegICCSegments(data)), img, nil
+		}
+
+	case "png":
+		img = applyOrientation(img, pngOrientation(data))
+		if err := png.Encode(&buf, img); err != nil {
+			return nil, nil, err
+		}
+		if KeepColorProfile {
+			// Блок iCCP должен идти сразу после IHDR (8 байт сигнатуры и 25 байт IHDR)
+			return insertAfter(buf.Bytes(), 33, pngChunk(data, "iCCP")), img, nil
+		}
+
+	case "webp":
+		return stripWebPMetadata(data, img)
+
+	default:
+		return data, img, nil
+	}
+	return buf.Bytes(), img, nil
+}
+
+// insertAfter вставляет данные в указанную позицию
+func insertAfter(data []byte, offset int, insert []byte) []byte {
+	if len(insert) == 0 {
+		return data
+	}
+	result := make([]byte, 0, len(data)+len(insert))
+	result = append(result, data[:offset]...)
+	result = append(result, insert...)
+	return append(result, data[offset:]...)
+}
+
+// jpegSegments перебирает сегменты заголовка JPEG до начала данных изображения
+func jpegSegments(data []byte, visit func(marker byte, segment []byte)) {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return
+	}
+	for i := 2; i+4 <= len(data) && data[i] == 0xFF; {
+		marker := data[i+1]
+		// Начало данных изображения: дальше метаданных нет
+		if marker == 0xDA {
+			return
+		}
+		length := int(binary.BigEndian.Uint16(data[i+2 : i+4]))
+		if length < 2 || i+2+length > len(data) {
+			return
+		}
+		visit(marker, data[i:i+2+length])
+		i += 2 + length
+	}
+}
+
+// jpegOrientation возвращает ориентацию из блока EXIF (APP1) файла JPEG
+func jpegOrientation(data []byte) int {
+	orientation := 1
+	jpegSegments(data, func(marker byte, segment []byte) {
+		if marker == 0xE1 && bytes.HasPrefix(segment[4:], []byte("Exif\x00\x00")) {
+			orientation = tiffOrientation(segment[10:])
+		}
+	})
+	return orientation
+}
+
+// jpegICCSegments возвращает сегменты APP2 с цветовым профилем ICC
+func jpegICCSegments(data []byte) []byte {
+	var profile []byte
+	jpegSegments(data, func(marker byte, segment []byte) {
+		if marker == 0xE2 && bytes.HasPrefix(segment[4:], []byte("ICC_PROFILE\x00")) {
+			profile = append(profile, segment...)
+		}
+	})
+	return profile
+}
+
+// pngChunk возвращает блок PNG указанного типа целиком (длина, тип, данные, CRC)
+func pngChunk(data []byte, chunkType string) []byte {
+	for i := 8; i+12 <= len(data); {
+		length := int(binary.BigEndian.Uint32(data[i : i+4]))
+		end := i + 12 + length
+		if length < 0 || end > len(data) {
+			return nil
+		}
+		if string(data[i+4:i+8]) == chunkType {
+			return data[i:end]
+		}
+		i = end
+	}
+	return nil
+}
+
+// pngOrientation возвращает ориентацию из блока eXIf файла PNG
+func pngOrientation(data []byte) int {
+	chunk := pngChunk(data, "eXIf")
+	if len(chunk) < 12 {
+		return 1
+	}
+	return tiffOrientation(chunk[8 : len(chunk)-4])
+}
+
+// tiffOrientation читает тег Orientation (0x0112) из первого каталога данных EXIF в формате TIFF
+func tiffOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	offset := int(order.Uint32(tiff[4:8]))
+	if offset+2 > len(tiff) {
+		return 1
+	}
+	count := int(order.Uint16(tiff[offset : offset+2]))
+	for i := 0; i < count; i++ {
+		entry := offset + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:entry+2]) == 0x0112 {
+			orientation := int(order.Uint16(tiff[entry+8 : entry+10]))
+			if orientation < 1 || orientation > 8 {
+				return 1
+			}
+			return orientation
+		}
+	}
+	return 1
+}
+
+// applyOrientation поворачивает и отражает изображение так, чтобы оно отображалось
+// правильно без тега ориентации
+func applyOrientation(img image.Image, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return img
+	}
+
+	src := img.Bounds()
+	width, height := src.Dx(), src.Dy()
+
+	// Ориентации 5–8 меняют местами ширину и высоту
+	dstWidth, dstHeight := width, height
+	if orientation >= 5 {
+		dstWidth, dstHeight = height, width
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // отражение по горизонтали
+				dx, dy = width-1-x, y
+			case 3: // поворот на 180°
+				dx, dy = width-1-x, height-1-y
+			case 4: // отражение по вертикали
+				dx, dy = x, height-1-y
+			case 5: // отражение относительно главной диагонали
+				dx, dy = y, x
+			case 6: // поворот на 90° по часовой стрелке
+				dx, dy = height-1-y, x
+			case 7: // отражение относительно побочной диагонали
+				dx, dy = height-1-y, width-1-x
+			case 8: // поворот на 90° против часовой стрелки
+				dx, dy = y, width-1-x
+			}
+			dst.Set(dx, dy, img.At(src.Min.X+x, src.Min.Y+y))
+		}
+	}
+	return dst
+}
@@ -0,0 +1,72 @@
+package utils
+
+import (
+	"strings"
+	"time"
+)
+
+// ImageStem возвращает общую часть имени оригинала изображения и всех его копий
+// ("card_<хэш>" для card_<хэш>.png, card_<хэш>_640w.png и card_<хэш>.png.webp).
+// Для файлов, которые не являются загруженными изображениями, возвращает false.
+func ImageStem(name string) (string, bool) {
+	kind := kindByFilename(name)
+	if kind == nil || !isUploadName(name) {
+		return "", false
+	}
+
+	id := strings.TrimPrefix(name, kind.Prefix+"_")
+	id, _, _ = strings.Cut(id, ".")
+	id, _, _ = strings.Cut(id, "_")
+	if id == "" {
+		return "", false
+	}
+	return kind.Prefix + "_" + id, true
+}
+
+// RemoveOrphanedImage удаляет файлы изображения, на которое не ссылаются ни карточки, ни профили.
+// files — все файлы с общей частью имени stem. Если изображение загружали повторно после before,
+// файлы не удаляются: новая ссылка на него может сохраниться в базе с минуты на минуту.
+func RemoveOrphanedImage(stem string, files []string, before time.Time) (bool, error) {
+	// Оригинал — файл без суффикса размера и без второго расширения
+	original := ""
+	for _, name := range files {
+		if rest, ok := strings.CutPrefix(name, stem+"."); ok && !strings.Contains(rest, ".") {
+			original = name
+			break
+		}
+	}
+
+	if original != "" {
+		unlock := lockImage(original)
+		defer unlock()
+
+		if unused, err := forgetImage(original, before); err != nil || !unused {
+			return false, err
+		}
+	} else {
+		// Остались только копии: учет ссылок проверяем для всех возможных расширений оригинала
+		for _, ext := range imageExtensions {
+			unlock := lockImage(stem + ext)
+			unused, err := forgetImage(stem+ext, before)
+			unlock()
+			if err != nil || !unused {
+				return false, err
+			}
+		}
+	}
+
+	for _, name := range files {
+		if err := removeVariant(name); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// forgetImage удаляет учет ссылок на неиспользуемый файл
+func forgetImage(filename string, before time.Time) (bool, error) {
+	if Refs == nil {
+		return true, nil
+	}
+	return Refs.Forget(filename, before)
+}
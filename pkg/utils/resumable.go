@@ -0,0 +1,187 @@
+package utils
+
+import (
+	"errors"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DefaultPartialUploadDir — директория с полученными частями возобновляемых загрузок по умолчанию
+const DefaultPartialUploadDir = "./partial"
+
+// PartialUploadDir — директория с полученными частями возобновляемых загрузок.
+// Части хранятся на диске, а не в хранилище файлов, поэтому загрузка переживает перезапуск
+// сервера, но видна только серверам с этой директорией: при нескольких серверах запросы
+// /api/uploads одной загрузки должны попадать на один сервер (sticky-маршрутизация),
+// либо директория должна быть общей для всех серверов (RESUMABLE_UPLOAD_DIR).
+var PartialUploadDir = DefaultPartialUploadDir
+
+// DefaultUploadExpiration — срок, за который нужно завершить и использовать загрузку
+const DefaultUploadExpiration = 24 * time.Hour
+
+// UploadExpiration — текущий срок хранения возобновляемых загрузок
+var UploadExpiration = DefaultUploadExpiration
+
+// ErrUploadOffset возвращается, если часть файла начинается не с того места,
+// на котором остановилась загрузка
+var ErrUploadOffset = errors.New("смещение не совпадает с полученным размером файла")
+
+// ErrUploadBusy возвращается, если в загрузку уже пишет другой запрос
+var ErrUploadBusy = errors.New("загрузка уже принимает другую часть файла")
+
+// writingUploads — загрузки, в которые сейчас пишутся части. Часть передается потоком
+// и может идти долго, поэтому запись в загрузку не ждет, а сразу завершается ошибкой.
+var writingUploads = struct {
+	sync.Mutex
+	ids map[string]bool
+}{ids: map[string]bool{}}
+
+// LoadUploadExpiration задает срок хранения возобновляемых загрузок из переменной окружения
+// RESUMABLE_UPLOAD_EXPIRATION (например, "24h"). При неверном значении используется значение по умолчанию.
+func LoadUploadExpiration() {
+	value := os.Getenv("RESUMABLE_UPLOAD_EXPIRATION")
+	if value == "" {
+		return
+	}
+
+	expiration, err := time.ParseDuration(value)
+	if err != nil || expiration <= 0 {
+		log.Printf("Неверное значение RESUMABLE_UPLOAD_EXPIRATION %q, используем %s", value, DefaultUploadExpiration)
+		expiration = DefaultUploadExpiration
+	}
+	UploadExpiration = expiration
+}
+
+// LoadPartialUploadDir задает директорию частей возобновляемых загрузок из переменной
+// окружения RESUMABLE_UPLOAD_DIR, например общий для нескольких серверов том
+func LoadPartialUploadDir() {
+	if value := os.Getenv("RESUMABLE_UPLOAD_DIR"); value != "" {
+		PartialUploadDir = value
+	}
+}
+
+// partialUploadPath возвращает путь к файлу загрузки. ID загрузки — UUID,
+// поэтому другие имена отклоняются, чтобы не выйти за пределы директории.
+func partialUploadPath(id string) (string, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return "", os.ErrNotExist
+	}
+	return filepath.Join(PartialUploadDir, id), nil
+}
+
+// PartialUploadSize возвращает количество полученных байт загрузки.
+// Файла еще нет, если не получено ни одной части.
+func PartialUploadSize(id string) (int64, error) {
+	path, err := partialUploadPath(id)
+	if err != nil {
+		return 0, err
+	}
+
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+// AppendPartialUpload дописывает часть файла, начинающуюся с offset, читая не больше limit байт,
+// и возвращает новый размер. Данные записываются по мере получения, поэтому при обрыве
+// соединения полученное начало части остается записанным, и клиент продолжает с нового размера.
+// Если загрузка уже получила другое количество байт, возвращается ErrUploadOffset.
+func AppendPartialUpload(id string, offset int64, r io.Reader, limit int64) (int64, error) {
+	path, err := partialUploadPath(id)
+	if err != nil {
+		return 0, err
+	}
+
+	writingUploads.Lock()
+	if writingUploads.ids[id] {
+		writingUploads.Unlock()
+		return 0, ErrUploadBusy
+	}
+	writingUploads.ids[id] = true
+	writingUploads.Unlock()
+	defer func() {
+		writingUploads.Lock()
+		delete(writingUploads.ids, id)
+		writingUploads.Unlock()
+	}()
+
+	if err := os.MkdirAll(PartialUploadDir, 0755); err != nil {
+		return 0, err
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+	if info.Size() != offset {
+		return info.Size(), ErrUploadOffset
+	}
+
+	written, err := io.Copy(file, io.LimitReader(r, limit))
+	if syncErr := file.Sync(); err == nil {
+		err = syncErr
+	}
+	return offset + written, err
+}
+
+// OpenPartialUpload открывает полученный файл загрузки для чтения
+func OpenPartialUpload(id string) (io.ReadCloser, error) {
+	path, err := partialUploadPath(id)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(path)
+}
+
+// RemovePartialUpload удаляет полученные части загрузки
+func RemovePartialUpload(id string) error {
+	path, err := partialUploadPath(id)
+	if err != nil {
+		return nil
+	}
+
+	// Запрос, который еще пишет часть, продолжит писать в удаленный файл, и она пропадет вместе с ним
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// ListPartialUploads возвращает ID загрузок, у которых есть файл на диске,
+// и время последнего изменения файла
+func ListPartialUploads() (map[string]time.Time, error) {
+	entries, err := os.ReadDir(PartialUploadDir)
+	if os.IsNotExist(err) {
+		return map[string]time.Time{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	uploads := map[string]time.Time{}
+	for _, entry := range entries {
+		if _, err := uuid.Parse(entry.Name()); err != nil || !entry.Type().IsRegular() {
+			continue
+		}
+		if info, err := entry.Info(); err == nil {
+			uploads[entry.Name()] = info.ModTime()
+		}
+	}
+	return uploads, nil
+}
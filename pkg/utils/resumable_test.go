@@ -0,0 +1,147 @@
+package utils
+
+import (
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+const testUploadID = "4f9c2a1e-7b3d-4c8e-9a5f-1d2e3f4a5b6c"
+
+func TestAppendPartialUpload(t *testing.T) {
+	// Шаги выполняются по порядку в одной загрузке
+	type step struct {
+		offset   int64
+		data     string
+		limit    int64
+		wantSize int64
+		wantErr  error
+	}
+
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name: "части дописываются друг за другом",
+			steps: []step{
+				{offset: 0, data: "hello", limit: 100, wantSize: 5},
+				{offset: 5, data: " world", limit: 100, wantSize: 11},
+			},
+		},
+		{
+			name: "первая часть не с нуля",
+			steps: []step{
+				{offset: 3, data: "abc", limit: 100, wantSize: 0, wantErr: ErrUploadOffset},
+			},
+		},
+		{
+			name: "смещение меньше полученного размера",
+			steps: []step{
+				{offset: 0, data: "hello", limit: 100, wantSize: 5},
+				{offset: 0, data: "hello", limit: 100, wantSize: 5, wantErr: ErrUploadOffset},
+			},
+		},
+		{
+			name: "смещение больше полученного размера",
+			steps: []step{
+				{offset: 0, data: "hello", limit: 100, wantSize: 5},
+				{offset: 10, data: "world", limit: 100, wantSize: 5, wantErr: ErrUploadOffset},
+			},
+		},
+		{
+			name: "часть обрезается по лимиту",
+			steps: []step{
+				{offset: 0, data: "hello world", limit: 5, wantSize: 5},
+				{offset: 5, data: " world", limit: 6, wantSize: 11},
+			},
+		},
+		{
+			name: "пустая часть",
+			steps: []step{
+				{offset: 0, data: "", limit: 100, wantSize: 0},
+				{offset: 0, data: "abc", limit: 0, wantSize: 0},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			PartialUploadDir = t.TempDir()
+			t.Cleanup(func() { PartialUploadDir = DefaultPartialUploadDir })
+
+			for i, s := range tt.steps {
+				size, err := AppendPartialUpload(testUploadID, s.offset, strings.NewReader(s.data), s.limit)
+				if !errors.Is(err, s.wantErr) {
+					t.Fatalf("шаг %d: AppendPartialUpload() error = %v, want %v", i, err, s.wantErr)
+				}
+				if size != s.wantSize {
+					t.Errorf("шаг %d: AppendPartialUpload() = %d, want %d", i, size, s.wantSize)
+				}
+
+				stored, err := PartialUploadSize(testUploadID)
+				if err != nil {
+					t.Fatalf("шаг %d: PartialUploadSize() error = %v", i, err)
+				}
+				if stored != s.wantSize {
+					t.Errorf("шаг %d: PartialUploadSize() = %d, want %d", i, stored, s.wantSize)
+				}
+			}
+		})
+	}
+}
+
+func TestAppendPartialUploadBusy(t *testing.T) {
+	PartialUploadDir = t.TempDir()
+	t.Cleanup(func() { PartialUploadDir = DefaultPartialUploadDir })
+
+	reader, writer := io.Pipe()
+	done := make(chan error)
+	go func() {
+		_, err := AppendPartialUpload(testUploadID, 0, reader, 100)
+		done <- err
+	}()
+
+	// Запись в канал завершается, только когда первая часть уже читается
+	if _, err := writer.Write([]byte("a")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := AppendPartialUpload(testUploadID, 1, strings.NewReader("b"), 100); !errors.Is(err, ErrUploadBusy) {
+		t.Errorf("AppendPartialUpload() во время записи error = %v, want %v", err, ErrUploadBusy)
+	}
+
+	writer.Close()
+	if err := <-done; err != nil {
+		t.Fatalf("AppendPartialUpload() error = %v", err)
+	}
+	// После завершения первой части загрузка снова принимает данные
+	if size, err := AppendPartialUpload(testUploadID, 1, strings.NewReader("b"), 100); err != nil || size != 2 {
+		t.Errorf("AppendPartialUpload() = %d, %v, want 2, nil", size, err)
+	}
+}
+
+func TestPartialUploadInvalidID(t *testing.T) {
+	PartialUploadDir = t.TempDir()
+	t.Cleanup(func() { PartialUploadDir = DefaultPartialUploadDir })
+
+	for _, id := range []string{"", "../secret", "not-a-uuid", testUploadID + "/.."} {
+		t.Run(id, func(t *testing.T) {
+			if _, err := AppendPartialUpload(id, 0, strings.NewReader("data"), 100); !errors.Is(err, os.ErrNotExist) {
+				t.Errorf("AppendPartialUpload(%q) error = %v, want %v", id, err, os.ErrNotExist)
+			}
+			if _, err := PartialUploadSize(id); !errors.Is(err, os.ErrNotExist) {
+				t.Errorf("PartialUploadSize(%q) error = %v, want %v", id, err, os.ErrNotExist)
+			}
+		})
+	}
+
+	entries, err := os.ReadDir(PartialUploadDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("в директории загрузок появились файлы: %v", entries)
+	}
+}
@@ -0,0 +1,307 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"log"
+	"mime"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/HugoSmits86/nativewebp"
+	"golang.org/x/image/draw"
+)
+
+// ImageSize описывает производный размер изображения. Если задана высота, изображение
+// обрезается по центру до точных размеров, иначе масштабируется по ширине с сохранением пропорций.
+type ImageSize struct {
+	Width  int
+	Height int
+}
+
+// Name возвращает обозначение размера в формате srcset ("640w")
+func (s ImageSize) Name() string {
+	return fmt.Sprintf("%dw", s.Width)
+}
+
+// JPEGQuality определяет качество производных изображений в формате JPEG
+const JPEGQuality = 85
+
+// imageKinds перечисляет виды изображений, для которых создаются производные размеры
+var imageKinds = []*ImageKind{&CardImage, &ProfileImage, &BannerImage}
+
+// ParseImageSizes разбирает список размеров через запятую: "640,1280" — ширины,
+// "600x200" — точные размеры с обрезкой
+func ParseImageSizes(value string) ([]ImageSize, error) {
+	sizes := []ImageSize{}
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		widthValue, heightValue, crop := strings.Cut(part, "x")
+		size := ImageSize{}
+		var err error
+		if size.Width, err = strconv.Atoi(widthValue); err != nil || size.Width <= 0 {
+			return nil, fmt.Errorf("неверная ширина %q", part)
+		}
+		if crop {
+			if size.Height, err = strconv.Atoi(heightValue); err != nil || size.Height <= 0 {
+				return nil, fmt.Errorf("неверная высота %q", part)
+			}
+		}
+		sizes = append(sizes, size)
+	}
+	return sizes, nil
+}
+
+// LoadImageSizes задает производные размеры из переменных окружения IMAGE_SIZES_CARD,
+// IMAGE_SIZES_PROFILE и IMAGE_SIZES_BANNER. Без переменной используются размеры по умолчанию.
+func LoadImageSizes() {
+	for _, kind := range imageKinds {
+		variable := "IMAGE_SIZES_" + strings.ToUpper(kind.Prefix)
+		value := os.Getenv(variable)
+		if value == "" {
+			continue
+		}
+
+		sizes, err := ParseImageSizes(value)
+		if err != nil {
+			log.Printf("Неверное значение %s %q: %v, используем размеры по умолчанию", variable, value, err)
+			continue
+		}
+		kind.Sizes = sizes
+	}
+}
+
+// kindByFilename определяет вид изображения по префиксу имени файла
+func kindByFilename(filename string) *ImageKind {
+	for _, kind := range imageKinds {
+		if strings.HasPrefix(filename, kind.Prefix+"_") {
+			return kind
+		}
+	}
+	return nil
+}
+
+// derivativeFilename возвращает имя файла производного размера. JPEG сохраняется в JPEG,
+// остальные форматы — в PNG, чтобы не терять прозрачность.
+func derivativeFilename(filename string, size ImageSize) string {
+	ext := filepath.Ext(filename)
+	derivativeExt := ".png"
+	if ext == ".jpg" {
+		derivativeExt = ".jpg"
+	}
+	return strings.TrimSuffix(filename, ext) + "_" + size.Name() + derivativeExt
+}
+
+// saveDerivatives создает производные размеры изображения рядом с оригиналом.
+// Размеры больше оригинала пропускаются, изображения не увеличиваются.
+func saveDerivatives(img image.Image, filename string, sizes []ImageSize) error {
+	bounds := img.Bounds()
+	for _, size := range sizes {
+		if size.Width > bounds.Dx() || size.Height > bounds.Dy() {
+			continue
+		}
+
+		resized := resizeImage(img, size)
+		name := derivativeFilename(filename, size)
+		var buf bytes.Buffer
+		if err := encodeImage(&buf, filepath.Ext(name), resized); err != nil {
+			return err
+		}
+		encodedSize := buf.Len()
+		if err := putVariant(name, &buf, mime.TypeByExtension(filepath.Ext(name))); err != nil {
+			return err
+		}
+
+		// Для копий в PNG сохраняем и WebP, который обычно меньше
+		if filepath.Ext(name) == ".png" {
+			if err := saveWebPAlternative(name, encodedSize, resized); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// resizeImage масштабирует изображение до размера, обрезая по центру лишнее при заданной высоте
+func resizeImage(img image.Image, size ImageSize) image.Image {
+	src := img.Bounds()
+
+	// Без высоты сохраняем пропорции оригинала
+	if size.Height == 0 {
+		height := max(1, src.Dy()*size.Width/src.Dx())
+		dst := image.NewRGBA(image.Rect(0, 0, size.Width, height))
+		draw.CatmullRom.Scale(dst, dst.Bounds(), img, src, draw.Src, nil)
+		return dst
+	}
+
+	// Вырезаем из центра оригинала область с нужными пропорциями
+	crop := src
+	if src.Dx()*size.Height > src.Dy()*size.Width {
+		width := src.Dy() * size.Width / size.Height
+		crop.Min.X += (src.Dx() - width) / 2
+		crop.Max.X = crop.Min.X + width
+	} else {
+		height := src.Dx() * size.Height / size.Width
+		crop.Min.Y += (src.Dy() - height) / 2
+		crop.Max.Y = crop.Min.Y + height
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, size.Width, size.Height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, crop, draw.Src, nil)
+	return dst
+}
+
+// encodeImage кодирует изображение в формат по расширению файла
+func encodeImage(w io.Writer, ext string, img image.Image) error {
+	switch ext {
+	case ".jpg":
+		return jpeg.Encode(w, img, &jpeg.Options{Quality: JPEGQuality})
+	case ".webp":
+		return nativewebp.Encode(w, img, nil)
+	default:
+		return png.Encode(w, img)
+	}
+}
+
+// ImageSrcSet возвращает URL производных размеров изображения по их обозначениям ("640w").
+// Размеры, которые не были созданы (например, для маленьких оригиналов), пропускаются.
+func ImageSrcSet(filename string) map[string]string {
+	kind := kindByFilename(filename)
+	if kind == nil {
+		return nil
+	}
+
+	srcset := map[string]string{}
+	for _, size := range kind.Sizes {
+		name := derivativeFilename(filename, size)
+		if variantExists(name) {
+			srcset[size.Name()] = ImageURL(name)
+		}
+	}
+	if len(srcset) == 0 {
+		return nil
+	}
+	return srcset
+}
+
+// removeDerivatives удаляет производные размеры изображения вместе с их копиями в WebP.
+// Удаляются размеры из текущих настроек; копии размеров, убранных из настроек, остаются.
+func removeDerivatives(filename string) {
+	kind := kindByFilename(filename)
+	if kind == nil {
+		return
+	}
+
+	for _, size := range kind.Sizes {
+		name := derivativeFilename(filename, size)
+		for _, variant := range []string{name, WebPAlternative(name)} {
+			if err := removeVariant(variant); err != nil {
+				log.Printf("Ошибка при удалении производного изображения: %v", err)
+			}
+		}
+	}
+}
+
+// maxVariantCacheSize ограничивает количество записей в кэше наличия производных копий
+const maxVariantCacheSize = 100_000
+
+// variantMissingTTL — сколько помнится отсутствие производной копии. Копию может создать
+// другой сервер (например, при повторной загрузке того же изображения), поэтому отсутствие
+// перепроверяется, но не чаще этого интервала: иначе каждый ответ с изображением
+// маленького оригинала проверял бы в хранилище все пропущенные размеры.
+const variantMissingTTL = 10 * time.Minute
+
+// variantCache запоминает, есть ли производные копии (уменьшенные или WebP) в хранилище:
+// в S3 каждая проверка — отдельный запрос. Наличие копии помнится до ее удаления,
+// отсутствие — до времени в missingUntil.
+var variantCache = struct {
+	sync.Mutex
+	exists       map[string]bool
+	missingUntil map[string]time.Time
+}{exists: map[string]bool{}, missingUntil: map[string]time.Time{}}
+
+// variantExists проверяет наличие производной копии с учетом кэша
+func variantExists(name string) bool {
+	now := time.Now()
+	variantCache.Lock()
+	exists := variantCache.exists[name]
+	missingUntil, missing := variantCache.missingUntil[name]
+	variantCache.Unlock()
+	if exists {
+		return true
+	}
+	if missing && now.Before(missingUntil) {
+		return false
+	}
+
+	if !isUploadName(name) {
+		return false
+	}
+	exists, err := Store.Exists(name)
+	if err != nil {
+		log.Printf("Ошибка проверки изображения %s: %v", name, err)
+		return false
+	}
+	if exists {
+		cacheVariant(name)
+	} else {
+		cacheMissingVariant(name, now.Add(variantMissingTTL))
+	}
+	return exists
+}
+
+// cacheVariant запоминает, что производная копия есть в хранилище
+func cacheVariant(name string) {
+	variantCache.Lock()
+	if len(variantCache.exists) >= maxVariantCacheSize {
+		variantCache.exists = map[string]bool{}
+	}
+	delete(variantCache.missingUntil, name)
+	variantCache.exists[name] = true
+	variantCache.Unlock()
+}
+
+// cacheMissingVariant запоминает до until, что производной копии нет в хранилище
+func cacheMissingVariant(name string, until time.Time) {
+	variantCache.Lock()
+	if len(variantCache.missingUntil) >= maxVariantCacheSize {
+		variantCache.missingUntil = map[string]time.Time{}
+	}
+	variantCache.missingUntil[name] = until
+	variantCache.Unlock()
+}
+
+// forgetVariant запоминает, что производной копии больше нет в хранилище
+func forgetVariant(name string) {
+	cacheMissingVariant(name, time.Now().Add(variantMissingTTL))
+	variantCache.Lock()
+	delete(variantCache.exists, name)
+	variantCache.Unlock()
+}
+
+// putVariant сохраняет производную копию в хранилище и запоминает ее в кэше
+func putVariant(name string, r io.Reader, contentType string) error {
+	if err := Store.Put(name, r, contentType); err != nil {
+		return err
+	}
+	cacheVariant(name)
+	return nil
+}
+
+// removeVariant удаляет производную копию из хранилища и из кэша
+func removeVariant(name string) error {
+	forgetVariant(name)
+	return Store.Delete(name)
+}
@@ -0,0 +1,83 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/user/roma/pkg/storage"
+)
+
+// countingStorage считает проверки наличия файлов в хранилище
+type countingStorage struct {
+	*storage.Local
+	exists int
+}
+
+func (s *countingStorage) Exists(name string) (bool, error) {
+	s.exists++
+	return s.Local.Exists(name)
+}
+
+func TestImageSrcSetCachesMissingVariants(t *testing.T) {
+	local, err := storage.NewLocal(t.TempDir(), "http://localhost/uploads/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := &countingStorage{Local: local}
+	previous := Store
+	Store = store
+	t.Cleanup(func() { Store = previous })
+
+	// У маленького оригинала создан только размер 640w, размера 1280w нет
+	const filename = "card_srcset_test.jpg"
+	if err := Store.Put(derivativeFilename(filename, ImageSize{Width: 640}), strings.NewReader("x"), "image/jpeg"); err != nil {
+		t.Fatal(err)
+	}
+	missing := derivativeFilename(filename, ImageSize{Width: 1280})
+
+	tests := []struct {
+		name       string
+		prepare    func()
+		want       []string
+		wantExists int // проверок в хранилище при этом вызове
+	}{
+		{"первый запрос проверяет все размеры", nil, []string{"640w"}, 2},
+		{"повторный запрос берет оба результата из кэша", nil, []string{"640w"}, 0},
+		{"отсутствие перепроверяется после истечения срока", func() {
+			cacheMissingVariant(missing, time.Now().Add(-time.Second))
+		}, []string{"640w"}, 1},
+		{"сохраненная копия сразу попадает в кэш", func() {
+			if err := putVariant(missing, strings.NewReader("x"), "image/jpeg"); err != nil {
+				t.Fatal(err)
+			}
+		}, []string{"640w", "1280w"}, 0},
+		{"удаленная копия не проверяется повторно", func() {
+			if err := removeVariant(missing); err != nil {
+				t.Fatal(err)
+			}
+		}, []string{"640w"}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.prepare != nil {
+				tt.prepare()
+			}
+			store.exists = 0
+
+			srcset := ImageSrcSet(filename)
+			if len(srcset) != len(tt.want) {
+				t.Errorf("ImageSrcSet() = %v, want sizes %v", srcset, tt.want)
+			}
+			for _, size := range tt.want {
+				if _, ok := srcset[size]; !ok {
+					t.Errorf("ImageSrcSet() = %v, want size %s", srcset, size)
+				}
+			}
+			if store.exists != tt.wantExists {
+				t.Errorf("проверок в хранилище = %d, want %d", store.exists, tt.wantExists)
+			}
+		})
+	}
+}
@@ -0,0 +1,293 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang.org/x/image/draw"
+)
+
+// ImageCacheDir — директория для изображений, преобразованных по запросу
+const ImageCacheDir = "./cache/img"
+
+// Способы вписывания изображения в заданные размеры
+const (
+	FitContain = "contain" // уменьшить, чтобы изображение поместилось целиком
+	FitCover   = "cover"   // заполнить размеры целиком, обрезав лишнее по центру
+)
+
+// DefaultTransformURL — адрес, по которому сервер отдает преобразованные изображения, по умолчанию
+const DefaultTransformURL = "http://localhost:4000/img/"
+
+// TransformBaseURL — текущий адрес преобразованных изображений, с которого начинаются подписанные ссылки
+var TransformBaseURL = DefaultTransformURL
+
+// LoadTransformURL задает адрес преобразованных изображений из переменной окружения
+// IMAGE_TRANSFORM_URL (например, "https://example.com/img/"), как STORAGE_PUBLIC_URL для загрузок
+func LoadTransformURL() {
+	if value := os.Getenv("IMAGE_TRANSFORM_URL"); value != "" {
+		TransformBaseURL = strings.TrimSuffix(value, "/") + "/"
+	}
+}
+
+// DefaultTransformSizes — допустимые ширины и высоты преобразованных изображений по умолчанию
+var DefaultTransformSizes = []int{64, 150, 320, 640, 960, 1280, 1920}
+
+// transformSizes — текущий список допустимых размеров
+var transformSizes = DefaultTransformSizes
+
+// LoadTransformSizes задает допустимые размеры преобразованных изображений из переменной
+// окружения IMAGE_TRANSFORM_SIZES (через запятую) или использует значения по умолчанию
+func LoadTransformSizes() {
+	value := os.Getenv("IMAGE_TRANSFORM_SIZES")
+	if value == "" {
+		return
+	}
+
+	sizes, err := ParseImageSizes(value)
+	if err != nil {
+		log.Printf("Неверное значение IMAGE_TRANSFORM_SIZES %q: %v, используем размеры по умолчанию", value, err)
+		return
+	}
+
+	allowed := []int{}
+	for _, size := range sizes {
+		allowed = append(allowed, size.Width)
+	}
+	transformSizes = allowed
+}
+
+// ImageTransform описывает преобразование изображения
+type ImageTransform struct {
+	Width  int    // 0 — по пропорциям
+	Height int    // 0 — по пропорциям
+	Fit    string // FitContain или FitCover
+	Format string // jpeg, png, webp или пусто (формат оригинала)
+}
+
+// ParseImageTransform проверяет параметры преобразования: размеры должны входить
+// в список допустимых, для FitCover нужны оба размера
+func ParseImageTransform(width, height, fit, format string) (ImageTransform, error) {
+	t := ImageTransform{Fit: fit, Format: format}
+	if t.Fit == "" {
+		t.Fit = FitContain
+	}
+
+	var err error
+	if width != "" {
+		if t.Width, err = strconv.Atoi(width); err != nil || !slices.Contains(transformSizes, t.Width) {
+			return t, &ImageError{Message: fmt.Sprintf("недопустимая ширина, допустимые размеры: %v", transformSizes)}
+		}
+	}
+	if height != "" {
+		if t.Height, err = strconv.Atoi(height); err != nil || !slices.Contains(transformSizes, t.Height) {
+			return t, &ImageError{Message: fmt.Sprintf("недопустимая высота, допустимые размеры: %v", transformSizes)}
+		}
+	}
+	if t.Width == 0 && t.Height == 0 {
+		return t, &ImageError{Message: "не указана ширина или высота"}
+	}
+
+	switch t.Fit {
+	case FitContain:
+	case FitCover:
+		if t.Width == 0 || t.Height == 0 {
+			return t, &ImageError{Message: "для fit=cover нужны ширина и высота"}
+		}
+	default:
+		return t, &ImageError{Message: "неизвестный способ вписывания, допустимо: contain, cover"}
+	}
+
+	switch t.Format {
+	case "", "jpeg", "png", "webp":
+	default:
+		return t, &ImageError{Message: "неподдерживаемый формат, допустимо: jpeg, png, webp"}
+	}
+
+	return t, nil
+}
+
+// query возвращает параметры преобразования в каноническом виде
+func (t ImageTransform) query() url.Values {
+	values := url.Values{}
+	if t.Width > 0 {
+		values.Set("w", strconv.Itoa(t.Width))
+	}
+	if t.Height > 0 {
+		values.Set("h", strconv.Itoa(t.Height))
+	}
+	values.Set("fit", t.Fit)
+	if t.Format != "" {
+		values.Set("format", t.Format)
+	}
+	return values
+}
+
+// signingKey возвращает ключ подписи ссылок на преобразованные изображения
+// из переменной окружения IMAGE_SIGNING_KEY или секретный ключ JWT
+func signingKey() []byte {
+	if key := os.Getenv("IMAGE_SIGNING_KEY"); key != "" {
+		return []byte(key)
+	}
+	return []byte(getSecretKey())
+}
+
+// SignImageTransform подписывает имя файла вместе с параметрами преобразования
+func SignImageTransform(filename string, t ImageTransform) string {
+	mac := hmac.New(sha256.New, signingKey())
+	mac.Write([]byte(filename + "?" + t.query().Encode()))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyImageTransform проверяет подпись параметров преобразования
+func VerifyImageTransform(filename string, t ImageTransform, signature string) bool {
+	return hmac.Equal([]byte(SignImageTransform(filename, t)), []byte(signature))
+}
+
+// TransformURL формирует подписанную ссылку на преобразованное изображение
+func TransformURL(baseURL, filename string, t ImageTransform) string {
+	values := t.query()
+	values.Set("sig", SignImageTransform(filename, t))
+	return baseURL + url.PathEscape(filename) + "?" + values.Encode()
+}
+
+// TransformedImage возвращает путь к преобразованному изображению в кэше и его ключ,
+// создавая изображение при первом запросе. Кэш хранится на диске каждого сервера
+// независимо от хранилища загрузок.
+func TransformedImage(filename string, t ImageTransform) (string, string, error) {
+	// Разрешаем только файлы из хранилища загрузок
+	if !isUploadName(filename) {
+		return "", "", os.ErrNotExist
+	}
+
+	sum := sha256.Sum256([]byte(filename + "?" + t.query().Encode()))
+	key := hex.EncodeToString(sum[:16])
+
+	format := t.Format
+	if format == "" {
+		format = "png"
+		if filepath.Ext(filename) == ".jpg" {
+			format = "jpeg"
+		}
+	}
+	ext := ".png"
+	switch format {
+	case "jpeg":
+		ext = ".jpg"
+	case "webp":
+		ext = ".webp"
+	}
+	path := filepath.Join(ImageCacheDir, key+ext)
+
+	// Удаленные изображения не отдаются, даже если их копии остались в кэше
+	exists, err := Store.Exists(filename)
+	if err != nil {
+		return "", "", err
+	}
+	if !exists {
+		return "", "", os.ErrNotExist
+	}
+
+	// Готовое изображение берем из кэша
+	if _, err := os.Stat(path); err == nil {
+		return path, key, nil
+	}
+
+	source, err := Store.Get(filename)
+	if err != nil {
+		return "", "", err
+	}
+	data, err := io.ReadAll(source)
+	source.Close()
+	if err != nil {
+		return "", "", err
+	}
+	img, err := decodeImage(data)
+	if err != nil {
+		return "", "", fmt.Errorf("ошибка декодирования изображения %s: %v", filename, err)
+	}
+
+	if err := os.MkdirAll(ImageCacheDir, 0755); err != nil {
+		return "", "", err
+	}
+
+	var buf bytes.Buffer
+	transformed := transformImage(img, t)
+	if err := encodeImage(&buf, ext, transformed); err != nil {
+		return "", "", err
+	}
+	if err := writeCacheFile(path, buf.Bytes()); err != nil {
+		return "", "", err
+	}
+
+	// Если формат не задан явно, рядом с PNG сохраняем копию в WebP для клиентов, которые его поддерживают
+	if t.Format == "" && format == "png" {
+		webp, smaller, err := smallerWebP(transformed, buf.Len())
+		if err == nil && smaller {
+			err = writeCacheFile(WebPAlternative(path), webp)
+		}
+		if err != nil {
+			log.Printf("Ошибка создания копии WebP для %s: %v", filename, err)
+		}
+	}
+
+	return path, key, nil
+}
+
+// writeCacheFile пишет файл кэша во временный и переименовывает его, чтобы параллельный
+// запрос не получил недописанное изображение
+func writeCacheFile(path string, data []byte) error {
+	tempPath := path + "." + GenerateRandomString(8) + ".tmp"
+	if err := os.WriteFile(tempPath, data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tempPath, path); err != nil {
+		os.Remove(tempPath)
+		return err
+	}
+	return nil
+}
+
+// transformImage изменяет размер изображения. Изображения не увеличиваются.
+func transformImage(img image.Image, t ImageTransform) image.Image {
+	src := img.Bounds()
+
+	if t.Fit == FitCover {
+		// Не увеличиваем: уменьшаем запрошенные размеры пропорционально, если оригинал меньше
+		size := ImageSize{Width: t.Width, Height: t.Height}
+		if size.Width > src.Dx() || size.Height > src.Dy() {
+			scale := min(float64(src.Dx())/float64(size.Width), float64(src.Dy())/float64(size.Height))
+			size.Width = max(1, int(float64(size.Width)*scale))
+			size.Height = max(1, int(float64(size.Height)*scale))
+		}
+		return resizeImage(img, size)
+	}
+
+	// Вписываем изображение целиком в заданные размеры
+	scale := 1.0
+	if t.Width > 0 {
+		scale = min(scale, float64(t.Width)/float64(src.Dx()))
+	}
+	if t.Height > 0 {
+		scale = min(scale, float64(t.Height)/float64(src.Dy()))
+	}
+	width := max(1, int(float64(src.Dx())*scale))
+	height := max(1, int(float64(src.Dy())*scale))
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, src, draw.Src, nil)
+	return dst
+}
@@ -0,0 +1,107 @@
+package utils
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestTransformURL(t *testing.T) {
+	t.Setenv("IMAGE_SIGNING_KEY", "test-key")
+
+	tests := []struct {
+		name      string
+		baseURL   string
+		filename  string
+		transform ImageTransform
+		wantURL   string
+	}{
+		{
+			name:      "ширина",
+			baseURL:   "http://localhost:4000/img/",
+			filename:  "photo.jpg",
+			transform: ImageTransform{Width: 320, Fit: FitContain},
+			wantURL:   "http://localhost:4000/img/photo.jpg?fit=contain&sig=",
+		},
+		{
+			name:      "оба размера и формат",
+			baseURL:   "https://cdn.example.com/img/",
+			filename:  "photo.png",
+			transform: ImageTransform{Width: 640, Height: 640, Fit: FitCover, Format: "webp"},
+			wantURL:   "https://cdn.example.com/img/photo.png?fit=cover&format=webp&h=640&sig=",
+		},
+		{
+			name:      "имя файла экранируется",
+			baseURL:   "http://localhost:4000/img/",
+			filename:  "my photo?.jpg",
+			transform: ImageTransform{Height: 150, Fit: FitContain},
+			wantURL:   "http://localhost:4000/img/my%20photo%3F.jpg?fit=contain&h=150&sig=",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := TransformURL(tt.baseURL, tt.filename, tt.transform)
+			if !strings.HasPrefix(got, tt.wantURL) {
+				t.Fatalf("TransformURL() = %q, want prefix %q", got, tt.wantURL)
+			}
+
+			// Параметры ссылки, разобранные как в обработчике, проходят проверку подписи
+			link, err := url.Parse(got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			query := link.Query()
+			parsed, err := ParseImageTransform(query.Get("w"), query.Get("h"), query.Get("fit"), query.Get("format"))
+			if err != nil {
+				t.Fatalf("ParseImageTransform() error = %v", err)
+			}
+			filename := strings.TrimPrefix(link.Path, strings.TrimPrefix(tt.baseURL, link.Scheme+"://"+link.Host))
+			if filename != tt.filename {
+				t.Errorf("имя файла в ссылке = %q, want %q", filename, tt.filename)
+			}
+			if !VerifyImageTransform(filename, parsed, query.Get("sig")) {
+				t.Error("подпись ссылки не прошла проверку")
+			}
+		})
+	}
+}
+
+func TestVerifyImageTransform(t *testing.T) {
+	t.Setenv("IMAGE_SIGNING_KEY", "test-key")
+
+	transform := ImageTransform{Width: 320, Height: 320, Fit: FitCover, Format: "webp"}
+	signature := SignImageTransform("photo.jpg", transform)
+
+	tests := []struct {
+		name      string
+		filename  string
+		transform ImageTransform
+		signature string
+		want      bool
+	}{
+		{"исходные параметры", "photo.jpg", transform, signature, true},
+		{"другая ширина", "photo.jpg", ImageTransform{Width: 1920, Height: 320, Fit: FitCover, Format: "webp"}, signature, false},
+		{"другая высота", "photo.jpg", ImageTransform{Width: 320, Height: 1920, Fit: FitCover, Format: "webp"}, signature, false},
+		{"другой способ вписывания", "photo.jpg", ImageTransform{Width: 320, Height: 320, Fit: FitContain, Format: "webp"}, signature, false},
+		{"другой формат", "photo.jpg", ImageTransform{Width: 320, Height: 320, Fit: FitCover}, signature, false},
+		{"другой файл", "other.jpg", transform, signature, false},
+		{"измененная подпись", "photo.jpg", transform, strings.ToUpper(signature), false},
+		{"обрезанная подпись", "photo.jpg", transform, signature[:len(signature)-1], false},
+		{"пустая подпись", "photo.jpg", transform, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := VerifyImageTransform(tt.filename, tt.transform, tt.signature); got != tt.want {
+				t.Errorf("VerifyImageTransform() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// Подпись с другим ключом не подходит
+	t.Setenv("IMAGE_SIGNING_KEY", "other-key")
+	if VerifyImageTransform("photo.jpg", transform, signature) {
+		t.Error("подпись прошла проверку с другим ключом")
+	}
+}
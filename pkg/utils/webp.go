@@ -0,0 +1,131 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+
+	"github.com/HugoSmits86/nativewebp"
+)
+
+// Флаги заголовка VP8X расширенного формата WebP
+const (
+	webpFlagICC  = 0x20
+	webpFlagEXIF = 0x08
+	webpFlagXMP  = 0x04
+)
+
+// WebPAlternative возвращает имя копии изображения в формате WebP
+func WebPAlternative(filename string) string {
+	return filename + ".webp"
+}
+
+// encodeWebP кодирует изображение в WebP без потерь. Сжатие с потерями nativewebp
+// не поддерживает, поэтому копии в WebP создаются только для PNG.
+func encodeWebP(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := nativewebp.Encode(&buf, img, nil); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// smallerWebP кодирует изображение в WebP и сообщает, меньше ли результат файла размера size.
+// WebP без потерь обычно заметно меньше PNG, но больше JPEG, поэтому для JPEG копия не создается.
+func smallerWebP(img image.Image, size int) ([]byte, bool, error) {
+	data, err := encodeWebP(img)
+	if err != nil {
+		return nil, false, err
+	}
+	return data, len(data) < size, nil
+}
+
+// saveWebPAlternative сохраняет в хранилище копию изображения в WebP, если копия меньше файла
+func saveWebPAlternative(filename string, size int, img image.Image) error {
+	data, smaller, err := smallerWebP(img, size)
+	if err != nil || !smaller {
+		return err
+	}
+	return putVariant(WebPAlternative(filename), bytes.NewReader(data), "image/webp")
+}
+
+// webpChunk — блок контейнера RIFF файла WebP
+type webpChunk struct {
+	fourCC  string
+	payload []byte
+}
+
+// webpChunks разбирает контейнер RIFF файла WebP на блоки
+func webpChunks(data []byte) ([]webpChunk, bool) {
+	if len(data) < 12 || string(data[:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return nil, false
+	}
+
+	chunks := []webpChunk{}
+	for i := 12; i+8 <= len(data); {
+		size := int(binary.LittleEndian.Uint32(data[i+4 : i+8]))
+		if size < 0 || i+8+size > len(data) {
+			return nil, false
+		}
+		chunks = append(chunks, webpChunk{fourCC: string(data[i : i+4]), payload: data[i+8 : i+8+size]})
+		// Блоки выравниваются по четной границе
+		i += 8 + size + size%2
+	}
+	return chunks, true
+}
+
+// stripWebPMetadata удаляет из WebP блоки EXIF и XMP (и ICCP, если цветовой профиль
+// не сохраняется) без перекодирования. Если по EXIF изображение нужно повернуть,
+// оно поворачивается и кодируется заново без потерь.
+func stripWebPMetadata(data []byte, img image.Image) ([]byte, image.Image, error) {
+	chunks, ok := webpChunks(data)
+	if !ok {
+		return nil, nil, &ImageError{Message: "поврежденный файл WebP"}
+	}
+
+	for _, chunk := range chunks {
+		if chunk.fourCC != "EXIF" {
+			continue
+		}
+		// Некоторые программы записывают данные EXIF с заголовком как в JPEG
+		tiff := bytes.TrimPrefix(chunk.payload, []byte("Exif\x00\x00"))
+		if orientation := tiffOrientation(tiff); orientation != 1 {
+			img = applyOrientation(img, orientation)
+			encoded, err := encodeWebP(img)
+			return encoded, img, err
+		}
+	}
+
+	removed := byte(webpFlagEXIF | webpFlagXMP)
+	if !KeepColorProfile {
+		removed |= webpFlagICC
+	}
+
+	var body bytes.Buffer
+	body.WriteString("WEBP")
+	for _, chunk := range chunks {
+		switch {
+		case chunk.fourCC == "EXIF" || chunk.fourCC == "XMP ":
+			continue
+		case chunk.fourCC == "ICCP" && !KeepColorProfile:
+			continue
+		}
+
+		payload := chunk.payload
+		if chunk.fourCC == "VP8X" && len(payload) > 0 {
+			payload = append([]byte{payload[0] &^ removed}, payload[1:]...)
+		}
+
+		body.WriteString(chunk.fourCC)
+		binary.Write(&body, binary.LittleEndian, uint32(len(payload)))
+		body.Write(payload)
+		if len(payload)%2 == 1 {
+			body.WriteByte(0)
+		}
+	}
+
+	result := make([]byte, 0, 8+body.Len())
+	result = append(result, "RIFF"...)
+	result = binary.LittleEndian.AppendUint32(result, uint32(body.Len()))
+	return append(result, body.Bytes()...), img, nil
+}